@@ -0,0 +1,131 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccessLogFormat selects the format of the access log
+type AccessLogFormat int
+
+const (
+	// AccessLogNone disables the access log
+	AccessLogNone AccessLogFormat = iota
+	// AccessLogJSON writes each request as a JSON object on its own line
+	AccessLogJSON
+	// AccessLogCommon writes the Apache common log format, followed by the duration in microseconds and the
+	// request ID
+	AccessLogCommon
+	// AccessLogCombined writes the Apache combined log format, followed by the duration in microseconds and the
+	// request ID
+	AccessLogCombined
+)
+
+// commonLogTime is the timestamp layout of the Apache log formats
+const commonLogTime = "02/Jan/2006:15:04:05 -0700"
+
+// WithAccessLogFormat writes a line per completed request to the access log in the format
+func WithAccessLogFormat(format AccessLogFormat) Option {
+	return func(o *Options) {
+		o.accessLogFormat = format
+	}
+}
+
+// WithAccessLogWriter sets where the access log is written, which defaults to standard output
+func WithAccessLogWriter(w io.Writer) Option {
+	return func(o *Options) {
+		o.accessLogWriter = w
+	}
+}
+
+// accessLogEntry is a line of the JSON access log
+type accessLogEntry struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id"`
+	ClientIP   string    `json:"client_ip"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Protocol   string    `json:"protocol"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	DurationMS float64   `json:"duration_ms"`
+	Referer    string    `json:"referer,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+// writeAccessLog writes the completed request to the access log
+func (s *service) writeAccessLog(request *Request, w *TrackingWriter, elapsed time.Duration) {
+	r := request.HTTPRequest()
+	var line []byte
+	switch s.accessLogFormat {
+	case AccessLogJSON:
+		data, err := json.Marshal(accessLogEntry{
+			Time:       request.start,
+			RequestID:  request.ID().String(),
+			ClientIP:   request.ClientIP(),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Protocol:   r.Proto,
+			Status:     w.Status(),
+			Bytes:      w.written,
+			DurationMS: float64(elapsed) / float64(time.Millisecond),
+			Referer:    r.Referer(),
+			UserAgent:  r.UserAgent(),
+		})
+		if err != nil {
+			request.Logger().ErrorContext(request.Context(), "error encoding access log", "error", err)
+			return
+		}
+		line = append(data, '\n')
+	case AccessLogCommon, AccessLogCombined:
+		line = []byte(fmt.Sprintf("%s - %s [%s] %q %d %s", request.ClientIP(), commonLogUser(r),
+			request.start.Format(commonLogTime), r.Method+" "+r.RequestURI+" "+r.Proto, w.Status(),
+			commonLogBytes(w.written)))
+		if s.accessLogFormat == AccessLogCombined {
+			line = append(line, fmt.Sprintf(" %q %q", r.Referer(), r.UserAgent())...)
+		}
+		line = append(line, fmt.Sprintf(" %d %s\n", elapsed.Microseconds(), request.ID())...)
+	default:
+		return
+	}
+	out := s.accessLogWriter
+	if out == nil {
+		out = os.Stdout
+	}
+	s.accessLogMu.Lock()
+	defer s.accessLogMu.Unlock()
+	if _, err := out.Write(line); err != nil {
+		request.Logger().ErrorContext(request.Context(), "error writing access log", "error", err)
+	}
+}
+
+// commonLogUser returns the basic auth user of the request, or - when there is none. The user is written bare, as
+// Apache does, with the characters that would split or forge the field escaped as \xhh.
+func commonLogUser(r *http.Request) string {
+	user, _, ok := r.BasicAuth()
+	if !ok || user == "" {
+		return "-"
+	}
+	var b strings.Builder
+	for i := 0; i < len(user); i++ {
+		if c := user[i]; c <= ' ' || c == 0x7f || c == '"' || c == '\\' {
+			fmt.Fprintf(&b, "\\x%02x", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func commonLogBytes(written int64) string {
+	if written == 0 {
+		return "-"
+	}
+	return strconv.FormatInt(written, 10)
+}
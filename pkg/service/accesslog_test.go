@@ -0,0 +1,61 @@
+package service
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestAccessLogFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  AccessLogFormat
+		pattern string
+	}{
+		{"json", AccessLogJSON, `^\{"time":"[^"]+","request_id":"[0-9a-f-]{36}","client_ip":"192.0.2.1","method":"GET",` +
+			`"path":"/x","protocol":"HTTP/1.1","status":201,"bytes":2,"duration_ms":[0-9.e-]+,"user_agent":"ua"\}\n$`},
+		{"common", AccessLogCommon, `^192.0.2.1 - alice \[\d\d/\w{3}/\d{4}:\d\d:\d\d:\d\d [+-]\d{4}\] ` +
+			`"GET /x\?q=1 HTTP/1.1" 201 2 \d+ [0-9a-f-]{36}\n$`},
+		{"combined", AccessLogCombined, `^192.0.2.1 - alice \[[^]]+\] "GET /x\?q=1 HTTP/1.1" 201 2 "" "ua" ` +
+			`\d+ [0-9a-f-]{36}\n$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			s := newTestService(WithAccessLogFormat(tt.format), WithAccessLogWriter(&logs))
+			s.GET("/x", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte("hi"))
+			})
+			// The request is sent as alice
+			header := map[string]string{"User-Agent": "ua", "Authorization": "Basic YWxpY2U6c2VjcmV0"}
+			serve(s, http.MethodGet, "/x?q=1", header)
+			if !regexp.MustCompile(tt.pattern).MatchString(logs.String()) {
+				t.Errorf("got %q, want a match for %s", logs.String(), tt.pattern)
+			}
+		})
+	}
+}
+
+func TestCommonLogUser(t *testing.T) {
+	tests := []struct {
+		user string
+		want string
+	}{
+		{"", "-"},
+		{"alice", "alice"},
+		{"bob smith", `bob\x20smith`},
+		{"eve\"\n", `eve\x22\x0a`},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.user != "" {
+			r.SetBasicAuth(tt.user, "secret")
+		}
+		if got := commonLogUser(r); got != tt.want {
+			t.Errorf("user %q: got %s, want %s", tt.user, got, tt.want)
+		}
+	}
+}
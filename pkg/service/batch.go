@@ -0,0 +1,145 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const (
+	// maxBatchRequests is the largest number of sub-requests accepted in one batch
+	maxBatchRequests = 100
+	// maxBatchBody is the largest batch request body accepted
+	maxBatchBody = 10 << 20
+)
+
+// batchRequestHeaders are the headers of the batch request that are not defaults for its sub-requests
+var batchRequestHeaders = []string{"Content-Length", "Content-Type", "Content-Encoding", "Transfer-Encoding", "Trailer",
+	IdempotencyKeyHeader}
+
+// WithBatchEndpoint serves an endpoint at the path accepting a POSTed JSON array of sub-requests, each with a method,
+// path, optional headers and optional JSON body. Each sub-request is dispatched in order through the service, with
+// the headers of the batch request as defaults, other than those describing its body and its Idempotency-Key, and the
+// endpoint responds with a JSON array of their statuses, headers and bodies.
+func WithBatchEndpoint(path string) Option {
+	return func(o *Options) {
+		o.batchPath = path
+	}
+}
+
+// BatchRequest is a sub-request of a batch
+type BatchRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResponse is the response to a sub-request of a batch. JSON bodies are embedded as is, and any other body as a
+// string.
+type BatchResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+func (s *service) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		s.MethodNotAllowed(w, r)
+		return
+	}
+	var batch []BatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBody)).Decode(&batch); err != nil {
+		s.BadRequest(w, r)
+		return
+	}
+	if len(batch) > maxBatchRequests {
+		s.ErrorResponse(w, r, http.StatusRequestEntityTooLarge, "Request Entity Too Large")
+		return
+	}
+	responses := make([]BatchResponse, 0, len(batch))
+	for _, sub := range batch {
+		responses = append(responses, s.serveBatchRequest(r, sub))
+	}
+	data, err := json.Marshal(responses)
+	if err != nil {
+		s.InternalServerError(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// serveBatchRequest dispatches a sub-request through the service and records its response
+func (s *service) serveBatchRequest(parent *http.Request, sub BatchRequest) BatchResponse {
+	if sub.Method == "" {
+		sub.Method = http.MethodGet
+	}
+	// Batches cannot be nested
+	if !strings.HasPrefix(sub.Path, "/") || strings.HasPrefix(sub.Path, s.batchPath) {
+		return BatchResponse{Status: http.StatusBadRequest}
+	}
+	r, err := http.NewRequestWithContext(parent.Context(), sub.Method, sub.Path, bytes.NewReader(sub.Body))
+	if err != nil {
+		return BatchResponse{Status: http.StatusBadRequest}
+	}
+	r.Header = parent.Header.Clone()
+	// Headers describing the batch body, or tied to the batch request as a whole, do not carry over to the
+	// sub-requests unless they set them themselves
+	for _, name := range batchRequestHeaders {
+		r.Header.Del(name)
+	}
+	if len(sub.Body) > 0 {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range sub.Headers {
+		r.Header.Set(name, value)
+	}
+	r.Host = parent.Host
+	r.RemoteAddr = parent.RemoteAddr
+	r.TLS = parent.TLS
+	recorder := &batchRecorder{header: make(http.Header)}
+	s.ServeHTTP(recorder, r)
+	response := BatchResponse{Status: recorder.status, Headers: make(map[string]string)}
+	if response.Status == 0 {
+		response.Status = http.StatusOK
+	}
+	for name := range recorder.header {
+		response.Headers[name] = recorder.header.Get(name)
+	}
+	body := recorder.body.Bytes()
+	switch {
+	case len(body) == 0:
+	case isJSONMediaType(recorder.header.Get("Content-Type")) && json.Valid(body):
+		response.Body = body
+	default:
+		response.Body, _ = json.Marshal(string(body))
+	}
+	return response
+}
+
+// batchRecorder records the response to a sub-request
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *batchRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *batchRecorder) WriteHeader(status int) {
+	if r.status == 0 && status >= 200 {
+		r.status = status
+	}
+}
+
+func (r *batchRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
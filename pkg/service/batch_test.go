@@ -0,0 +1,100 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postBatch posts the batch with the headers and returns the decoded sub-responses
+func postBatch(t *testing.T, h http.Handler, body string, header map[string]string) []BatchResponse {
+	t.Helper()
+	w := post(h, "/batch", body, header)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", w.Code)
+	}
+	var responses []BatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &responses); err != nil {
+		t.Fatal(err)
+	}
+	return responses
+}
+
+func TestBatch(t *testing.T) {
+	s := newTestService(WithBatchEndpoint("/batch"))
+	s.GET("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		s.OK(w, r, map[string]string{"id": request.PathValue("id"), "auth": r.Header.Get("Authorization")})
+	})
+	s.POST("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	})
+	body := `[{"method":"GET","path":"/users/7"},{"method":"POST","path":"/echo","body":{"a":1}},{"path":"/missing"},
+		{"path":"/batch"}]`
+	responses := postBatch(t, s.mux, body, map[string]string{"Authorization": "Bearer token"})
+	want := []struct {
+		status int
+		body   string
+	}{
+		{http.StatusOK, `{"auth":"Bearer token","id":"7"}`},
+		{http.StatusCreated, `"{\"a\":1}"`},
+		{http.StatusNotFound, ""},
+		{http.StatusBadRequest, ""},
+	}
+	if len(responses) != len(want) {
+		t.Fatalf("got %d responses, want %d", len(responses), len(want))
+	}
+	for i, response := range responses {
+		if response.Status != want[i].status || want[i].body != "" && string(response.Body) != want[i].body {
+			t.Errorf("response %d: got status %d with body %s, want %d with %s", i, response.Status, response.Body,
+				want[i].status, want[i].body)
+		}
+	}
+	if w := serve(s.mux, http.MethodGet, "/batch", nil); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status %d for GET, want 405", w.Code)
+	}
+}
+
+func TestBatchHeaders(t *testing.T) {
+	s := newTestService(WithBatchEndpoint("/batch"), WithIdempotency(NewMemoryIdempotencyStore(), time.Minute))
+	s.POST("/headers", func(w http.ResponseWriter, r *http.Request) {
+		names := []string{"Content-Encoding", "Content-Type", IdempotencyKeyHeader, "Authorization"}
+		values := make([]string, 0, len(names))
+		for _, name := range names {
+			values = append(values, name+"="+r.Header.Get(name))
+		}
+		_, _ = w.Write([]byte(strings.Join(values, " ")))
+	})
+	body := `[{"method":"POST","path":"/headers"},
+		{"method":"POST","path":"/headers","headers":{"Idempotency-Key":"sub"}},
+		{"method":"POST","path":"/headers","body":{"a":1}}]`
+	header := map[string]string{"Content-Type": "application/json", IdempotencyKeyHeader: "batch",
+		"Authorization": "Bearer token"}
+	responses := postBatch(t, s.mux, body, header)
+	want := []string{
+		`"Content-Encoding= Content-Type= Idempotency-Key= Authorization=Bearer token"`,
+		`"Content-Encoding= Content-Type= Idempotency-Key=sub Authorization=Bearer token"`,
+		`"Content-Encoding= Content-Type=application/json Idempotency-Key= Authorization=Bearer token"`,
+	}
+	if len(responses) != len(want) {
+		t.Fatalf("got %d responses, want %d", len(responses), len(want))
+	}
+	for i, response := range responses {
+		if string(response.Body) != want[i] {
+			t.Errorf("response %d: got %s, want %s", i, response.Body, want[i])
+		}
+	}
+	r := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`[{"method":"POST","path":"/headers"}]`))
+	r.Header.Set("Content-Encoding", "identity")
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, r)
+	if strings.Contains(w.Body.String(), "Content-Encoding=identity") {
+		t.Errorf("got %s, want the batch Content-Encoding dropped", w.Body.String())
+	}
+}
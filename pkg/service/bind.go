@@ -0,0 +1,119 @@
+package service
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+var (
+	// ErrBind is returned when request parameters cannot be bound to a struct
+	ErrBind = errors.New("invalid request parameter")
+	// ErrDuplicateParameter is returned when a parameter bound to a single valued field is given more than once
+	ErrDuplicateParameter = errors.New("parameter given more than once")
+)
+
+// BindError describes a request parameter that could not be bound
+type BindError struct {
+	Parameter string
+	Err       error
+}
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("%s %q: %v", ErrBind, e.Parameter, e.Err)
+}
+
+func (e *BindError) Unwrap() []error {
+	return []error{ErrBind, e.Err}
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// Bind populates the struct pointed to by v from the query and form parameters. Fields are matched by their form
+// tag, or their name when untagged, and fields tagged "-" are skipped. Slice fields collect every value of their
+// parameter; any other field given more than one value is rejected with ErrDuplicateParameter.
+func (r *Request) Bind(v interface{}) error {
+	if err := r.httpRequest.ParseForm(); err != nil {
+		// The parse error is wrapped too, so that a body over the limit is still reported as too large
+		return fmt.Errorf("%w: %w", ErrBind, err)
+	}
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind target must be a pointer to a struct, got %T", v)
+	}
+	target = target.Elem()
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Type().Field(i)
+		name := field.Tag.Get("form")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		values, ok := r.httpRequest.Form[name]
+		if !ok {
+			continue
+		}
+		if err := bindField(target.Field(i), values); err != nil {
+			return &BindError{Parameter: name, Err: err}
+		}
+	}
+	return nil
+}
+
+func bindField(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && !field.Addr().Type().Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := bindValue(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	if len(values) > 1 {
+		return ErrDuplicateParameter
+	}
+	return bindValue(field, values[0])
+}
+
+func bindValue(field reflect.Value, value string) error {
+	if field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	type params struct {
+		ID      int      `form:"id"`
+		Tags    []string `form:"tag"`
+		Name    string
+		Active  bool   `form:"active"`
+		Ignored string `form:"-"`
+	}
+	tests := []struct {
+		query string
+		want  params
+		err   error
+	}{
+		{"id=3&tag=a&tag=b&Name=x&active=true&-=y", params{ID: 3, Tags: []string{"a", "b"}, Name: "x", Active: true}, nil},
+		{"id=3&id=4", params{}, ErrDuplicateParameter},
+		{"id=three", params{}, ErrBind},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			var got params
+			err := NewRequest(context.Background(), r, httptest.NewRecorder()).Bind(&got)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) || StatusFromError(err) != http.StatusBadRequest {
+					t.Errorf("got error %v with status %d, want %v with 400", err, StatusFromError(err), tt.err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v with error %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindFormTooLarge(t *testing.T) {
+	s := newTestService(WithMaxRequestBody(16))
+	s.POST("/form", func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		var form struct {
+			Name string `form:"name"`
+		}
+		if err := request.Bind(&form); err != nil {
+			status := StatusFromError(err)
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		_, _ = w.Write([]byte(form.Name))
+	})
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"within the limit", "name=alice", http.StatusOK},
+		{"over the limit", "name=" + strings.Repeat("a", 32), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The body is sent without a length, so the limit is only found while the form is parsed
+			r := httptest.NewRequest(http.MethodPost, "/form", io.MultiReader(strings.NewReader(tt.body)))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, r)
+			if w.Code != tt.code {
+				t.Errorf("got status %d, want %d", w.Code, tt.code)
+			}
+		})
+	}
+}
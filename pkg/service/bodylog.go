@@ -0,0 +1,110 @@
+package service
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// BodyLogging configures the capture of request and response bodies
+type BodyLogging struct {
+	// MaxBytes caps the number of bytes of each body that is logged
+	MaxBytes int
+	// Redact, if set, masks sensitive content in a captured body before it is logged
+	Redact func(body []byte) []byte
+}
+
+// WithBodyLogging logs the request and response bodies of every routed request with the request logger
+func WithBodyLogging(bodyLogging BodyLogging) Option {
+	return func(o *Options) {
+		o.bodyLogging = &bodyLogging
+	}
+}
+
+// RedactJSONFields returns a redaction function masking the values of the named JSON fields. It works on truncated
+// bodies as well as complete documents.
+func RedactJSONFields(fields ...string) func(body []byte) []byte {
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = regexp.QuoteMeta(field)
+	}
+	pattern := regexp.MustCompile(`("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	return func(body []byte) []byte {
+		return pattern.ReplaceAll(body, []byte(`$1"***"`))
+	}
+}
+
+// limitedBuffer keeps the first max bytes written to it, recording whether anything was dropped
+type limitedBuffer struct {
+	data      []byte
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.max - len(b.data); remaining < len(p) {
+		b.data = append(b.data, p[:max(remaining, 0)]...)
+		b.truncated = true
+	} else {
+		b.data = append(b.data, p...)
+	}
+	return len(p), nil
+}
+
+// bodyCaptureWriter copies the response body into a limited buffer as it is written
+type bodyCaptureWriter struct {
+	http.ResponseWriter
+	body *limitedBuffer
+}
+
+func (w *bodyCaptureWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	_, _ = w.body.Write(b[:n])
+	return n, err
+}
+
+func (w *bodyCaptureWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *bodyCaptureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func bodyLoggingMiddleware(cfg BodyLogging) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestBody := &limitedBuffer{max: cfg.MaxBytes}
+			responseBody := &limitedBuffer{max: cfg.MaxBytes}
+			if r.Body != nil && r.Body != http.NoBody {
+				// Tee the body so the handler still reads all of it
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.TeeReader(r.Body, requestBody), r.Body}
+			}
+			next.ServeHTTP(&bodyCaptureWriter{ResponseWriter: w, body: responseBody}, r)
+			logger := slog.Default()
+			if request, ok := RequestFromContext(r.Context()); ok {
+				logger = request.Logger()
+			}
+			logger.InfoContext(r.Context(), "request bodies",
+				slog.String("request_body", cfg.redact(requestBody.data)),
+				slog.Bool("request_body_truncated", requestBody.truncated),
+				slog.String("response_body", cfg.redact(responseBody.data)),
+				slog.Bool("response_body_truncated", responseBody.truncated),
+			)
+		})
+	}
+}
+
+func (cfg BodyLogging) redact(body []byte) string {
+	if cfg.Redact != nil {
+		body = cfg.Redact(body)
+	}
+	return string(body)
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedactJSONFields(t *testing.T) {
+	redact := RedactJSONFields("password", "token")
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"user":"a","password":"secret"}`, `{"user":"a","password":"***"}`},
+		{`{"token": 12345, "n": 1}`, `{"token": "***", "n": 1}`},
+		{`{"password":"trunc`, `{"password":"***"`},
+		{`{"user":"a"}`, `{"user":"a"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			if got := string(redact([]byte(tt.body))); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBodyLogging(t *testing.T) {
+	logs := captureLogs(t)
+	s := newTestService(WithBodyLogging(BodyLogging{MaxBytes: 40, Redact: RedactJSONFields("password")}))
+	s.POST("/login", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+		_, _ = w.Write([]byte(strings.Repeat("x", 50)))
+	})
+	const body = `{"user":"a","password":"secret"}`
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body)))
+	if !strings.HasPrefix(w.Body.String(), body) {
+		t.Errorf("got response %q, want the handler to read the whole request body", w.Body.String())
+	}
+	out := logs.String()
+	if strings.Contains(out, "secret") {
+		t.Errorf("got logs %q, want the password redacted", out)
+	}
+	if !strings.Contains(out, "request_body_truncated=false") || !strings.Contains(out, "response_body_truncated=true") {
+		t.Errorf("got logs %q, want only the response body truncated", out)
+	}
+}
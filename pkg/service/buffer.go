@@ -0,0 +1,115 @@
+package service
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+)
+
+// DefaultResponseBufferLimit is the number of bytes buffered per response when response buffering is enabled
+const DefaultResponseBufferLimit int64 = 1 << 20
+
+// WithResponseBuffering holds the response in memory until the handler returns, so that a handler failing part way
+// through its response can still replace it with an error status. A later call to WriteHeader discards the body
+// buffered so far. Responses outgrowing the buffer limit, or flushed by the handler, are streamed through as usual.
+func WithResponseBuffering(responseBuffering bool) Option {
+	return func(o *Options) {
+		o.responseBuffering = responseBuffering
+	}
+}
+
+// WithResponseBufferLimit sets the number of bytes buffered per response before it is streamed through to the client
+func WithResponseBufferLimit(responseBufferLimit int64) Option {
+	return func(o *Options) {
+		o.responseBufferLimit = responseBufferLimit
+	}
+}
+
+// bufferedWriter holds the status and body of a response until it is finished or outgrows the limit
+type bufferedWriter struct {
+	http.ResponseWriter
+	limit     int64
+	status    int
+	body      bytes.Buffer
+	streaming bool
+}
+
+func newBufferedWriter(w http.ResponseWriter, limit int64) *bufferedWriter {
+	return &bufferedWriter{ResponseWriter: w, limit: limit}
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	switch {
+	case w.streaming || status < 200 && status != http.StatusSwitchingProtocols:
+		w.ResponseWriter.WriteHeader(status)
+	case status == http.StatusSwitchingProtocols:
+		w.status = status
+		w.stream()
+	default:
+		// A later status replaces the response buffered so far
+		w.status = status
+		w.body.Reset()
+	}
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if !w.streaming && int64(w.body.Len()+len(b)) > w.limit {
+		w.stream()
+	}
+	if w.streaming {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) Flush() {
+	w.stream()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer
+func (w *bufferedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// stream writes the buffered response and passes anything written afterwards straight through
+func (w *bufferedWriter) stream() {
+	if w.streaming {
+		return
+	}
+	w.streaming = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+		w.body.Reset()
+	}
+}
+
+// discard drops the response buffered so far so that another can replace it, reporting false once part of the
+// response has been sent
+func (w *bufferedWriter) discard() bool {
+	if w.streaming {
+		return false
+	}
+	w.status = 0
+	w.body.Reset()
+	w.Header().Del("Content-Length")
+	return true
+}
+
+// finish sends the buffered response once the handler has returned
+func (w *bufferedWriter) finish() {
+	if w.streaming || w.status == 0 && w.body.Len() == 0 {
+		return
+	}
+	// A response with trailers has to be chunked, so it is sent without a length
+	if w.Header().Get("Content-Length") == "" && w.Header().Get("Trailer") == "" && w.body.Len() > 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(w.body.Len()))
+	}
+	w.stream()
+}
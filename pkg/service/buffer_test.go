@@ -0,0 +1,66 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestResponseBuffering(t *testing.T) {
+	failPartWay := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		http.Error(w, "failed", http.StatusInternalServerError)
+	}
+	tests := []struct {
+		name string
+		opts []Option
+		code int
+		body string
+	}{
+		{"buffered", []Option{WithResponseBuffering(true)}, http.StatusInternalServerError, "failed\n"},
+		{"over the limit", []Option{WithResponseBuffering(true), WithResponseBufferLimit(3)}, http.StatusOK, "partial"},
+		{"unbuffered", nil, http.StatusOK, "partial"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(tt.opts...)
+			s.GET("/", failPartWay)
+			w := serve(s.mux, http.MethodGet, "/", nil)
+			if w.Code != tt.code || !strings.HasPrefix(w.Body.String(), tt.body) {
+				t.Errorf("got status %d with body %q, want %d with %q", w.Code, w.Body.String(), tt.code, tt.body)
+			}
+		})
+	}
+}
+
+func TestResponseBufferingPanic(t *testing.T) {
+	panicPartWay := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		_, _ = w.Write([]byte("partial"))
+		panic("boom")
+	}
+	tests := []struct {
+		name string
+		opts []Option
+		code int
+		body string
+	}{
+		{"buffered", []Option{WithResponseBuffering(true)}, http.StatusInternalServerError, "Internal Server Error\n"},
+		{"recover handler", []Option{WithResponseBuffering(true), WithRecoverHandler(func(r *Request, recovered interface{}) {
+			r.Writer().WriteHeader(http.StatusServiceUnavailable)
+			_, _ = r.Writer().Write([]byte("try again"))
+		})}, http.StatusServiceUnavailable, "try again"},
+		{"over the limit", []Option{WithResponseBuffering(true), WithResponseBufferLimit(3)}, http.StatusOK, "partial"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = captureLogs(t)
+			s := newTestService(tt.opts...)
+			s.GET("/", panicPartWay)
+			w := serve(s.mux, http.MethodGet, "/", nil)
+			if w.Code != tt.code || w.Body.String() != tt.body {
+				t.Errorf("got status %d with body %q, want %d with %q", w.Code, w.Body.String(), tt.code, tt.body)
+			}
+		})
+	}
+}
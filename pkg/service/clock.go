@@ -0,0 +1,6 @@
+package service
+
+import "github.com/bchisham/http-simple/pkg/service/internal/clock"
+
+// clk is the time source of expiry logic. It is a variable so that tests can replace it with a fake clock.
+var clk clock.Clock = clock.Real{}
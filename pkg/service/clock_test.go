@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"github.com/bchisham/http-simple/pkg/service/internal/clock"
+	"testing"
+	"time"
+)
+
+// useFakeClock replaces the time source with a fake clock for the rest of the test
+func useFakeClock(t *testing.T) *clock.Fake {
+	t.Helper()
+	fake := clock.NewFake(time.Unix(1000, 0))
+	clk = fake
+	t.Cleanup(func() {
+		clk = clock.Real{}
+	})
+	return fake
+}
+
+func TestIdempotencyStoreExpiry(t *testing.T) {
+	fake := useFakeClock(t)
+	store := NewMemoryIdempotencyStore()
+	if err := store.Set(context.Background(), "key", &CachedResponse{Status: 201}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	fake.Advance(59 * time.Second)
+	if _, ok, _ := store.Get(context.Background(), "key"); !ok {
+		t.Fatal("got no response before the ttl, want the stored one")
+	}
+	fake.Advance(2 * time.Second)
+	if _, ok, _ := store.Get(context.Background(), "key"); ok {
+		t.Error("got a response after the ttl, want none")
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestCompressibleTypes(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []Option
+		contentType string
+		accept      string
+		compressed  bool
+	}{
+		{"JSON", nil, "application/json; charset=utf-8", "gzip", true},
+		{"text subtype", nil, "text/html", "gzip, deflate", true},
+		{"JPEG", nil, "image/jpeg", "gzip", false},
+		{"not accepted", nil, "application/json", "identity", false},
+		{"refused", nil, "application/json", "gzip;q=0", false},
+		{"custom type", []Option{WithCompressibleTypes([]string{"image/*"})}, "image/jpeg", "gzip", true},
+		{"replaced default", []Option{WithCompressibleTypes([]string{"image/*"})}, "application/json", "gzip", false},
+	}
+	body := "compressible body compressible body compressible body"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(append(tt.opts, WithCompression(true))...)
+			s.GET("/", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				_, _ = w.Write([]byte(body))
+			})
+			w := serve(s.mux, http.MethodGet, "/", map[string]string{"Accept-Encoding": tt.accept})
+			if compressed := w.Header().Get("Content-Encoding") == "gzip"; compressed != tt.compressed {
+				t.Fatalf("got compressed %v, want %v", compressed, tt.compressed)
+			}
+			var reader io.Reader = w.Body
+			if tt.compressed {
+				gz, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				reader = gz
+			}
+			got, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != body {
+				t.Errorf("got body %q, want %q", got, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"net/http"
+	"time"
+)
+
+// WithMaxConcurrentRequests limits the number of requests handled at once. Requests beyond the limit wait for the
+// time set with WithConcurrencyWaitTimeout, and are rejected with 503 if no slot frees up in time.
+func WithMaxConcurrentRequests(maxConcurrentRequests int) Option {
+	return func(o *Options) {
+		o.maxConcurrentRequests = maxConcurrentRequests
+	}
+}
+
+// WithConcurrencyWaitTimeout sets how long a request over the concurrency limit waits for a slot. By default such
+// requests are rejected immediately.
+func WithConcurrencyWaitTimeout(concurrencyWaitTimeout time.Duration) Option {
+	return func(o *Options) {
+		o.concurrencyWaitTimeout = concurrencyWaitTimeout
+	}
+}
+
+// WithConcurrencyLimitExcludesStreaming exempts streaming routes, those registered with a zero route timeout, from
+// the concurrency limit so that long-lived streams do not hold slots needed by short requests
+func WithConcurrencyLimitExcludesStreaming(excludeStreaming bool) Option {
+	return func(o *Options) {
+		o.concurrencyExcludeStreaming = excludeStreaming
+	}
+}
+
+// concurrencyLimitMiddleware holds a slot of a semaphore sized to the concurrency limit while the request is handled
+func (s *service) concurrencyLimitMiddleware() Middleware {
+	slots := make(chan struct{}, s.maxConcurrentRequests)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if request, ok := RequestFromContext(r.Context()); ok && s.concurrencyExcludeStreaming {
+				if _, streaming := s.effectiveTimeout(request); streaming {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			if !acquire(r, slots, s.concurrencyWaitTimeout) {
+				s.ServiceUnavailable(w, r)
+				return
+			}
+			defer func() { <-slots }()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// acquire takes a slot, waiting up to the timeout for one to free up while the request is still wanted
+func acquire(r *http.Request, slots chan struct{}, timeout time.Duration) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+	}
+	if timeout <= 0 {
+		return false
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		wait time.Duration
+		code int
+	}{
+		{"reject", 0, http.StatusServiceUnavailable},
+		{"wait for a slot", 5 * time.Second, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(WithMaxConcurrentRequests(2), WithConcurrencyWaitTimeout(tt.wait),
+				WithConcurrencyLimitExcludesStreaming(true))
+			entered := make(chan struct{}, 10)
+			release := make(chan struct{})
+			releaseStream := make(chan struct{})
+			s.GET("/slow", func(w http.ResponseWriter, r *http.Request) {
+				entered <- struct{}{}
+				<-release
+			})
+			s.GET("/stream", func(w http.ResponseWriter, r *http.Request) {
+				entered <- struct{}{}
+				<-releaseStream
+			}, WithRouteTimeout(0))
+			codes := make(chan int, 10)
+			get := func(path string) {
+				codes <- serve(s.mux, http.MethodGet, path, nil).Code
+			}
+			go get("/slow")
+			go get("/slow")
+			<-entered
+			<-entered
+			// Streaming routes are exempt from the limit
+			go get("/stream")
+			<-entered
+			go get("/slow")
+			if tt.wait == 0 {
+				if code := <-codes; code != tt.code {
+					t.Errorf("got status %d over the limit, want %d", code, tt.code)
+				}
+				close(release)
+			} else {
+				release <- struct{}{}
+				<-entered
+				close(release)
+			}
+			close(releaseStream)
+			for i := 0; i < 3; i++ {
+				if code := <-codes; code != http.StatusOK {
+					t.Errorf("got status %d, want 200", code)
+				}
+			}
+			if tt.wait > 0 {
+				if code := <-codes; code != tt.code {
+					t.Errorf("got status %d after waiting, want %d", code, tt.code)
+				}
+			}
+		})
+	}
+}
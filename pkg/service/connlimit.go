@@ -0,0 +1,84 @@
+package service
+
+import (
+	"log/slog"
+	"net"
+	"sync"
+)
+
+// WithMaxConnsPerIP caps the number of connections open at once from a single client IP. Connections beyond the cap
+// are closed as soon as they are accepted, before any request is read, so a single client cannot exhaust the server's
+// connections. Unlike rate limiting, it counts connections rather than requests. Zero or less leaves connections
+// uncapped.
+func WithMaxConnsPerIP(maxConnsPerIP int) Option {
+	return func(o *Options) {
+		o.maxConnsPerIP = maxConnsPerIP
+	}
+}
+
+// perIPListener refuses connections from client IPs already holding the maximum number of open connections
+type perIPListener struct {
+	net.Listener
+	max   int
+	mu    sync.Mutex
+	conns map[string]int
+}
+
+func newPerIPListener(listener net.Listener, max int) *perIPListener {
+	return &perIPListener{Listener: listener, max: max, conns: make(map[string]int)}
+}
+
+func (l *perIPListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		ip := connIP(conn)
+		if l.acquire(ip) {
+			return &perIPConn{Conn: conn, release: func() { l.release(ip) }}, nil
+		}
+		slog.Debug("connection refused, too many connections from client", "ip", ip)
+		_ = conn.Close()
+	}
+}
+
+func (l *perIPListener) acquire(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conns[ip] >= l.max {
+		return false
+	}
+	l.conns[ip]++
+	return true
+}
+
+func (l *perIPListener) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conns[ip]--; l.conns[ip] <= 0 {
+		delete(l.conns, ip)
+	}
+}
+
+// connIP returns the IP of the remote end of the connection, or its whole address when it has no port
+func connIP(conn net.Conn) string {
+	addr := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// perIPConn releases its client's slot when first closed
+type perIPConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *perIPConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
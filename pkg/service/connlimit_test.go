@@ -0,0 +1,57 @@
+package service
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// getHealth sends a health request over the connection and returns the response status
+func getHealth(conn net.Conn) (int, error) {
+	if _, err := conn.Write([]byte("GET /health HTTP/1.1\r\nHost: localhost\r\n\r\n")); err != nil {
+		return 0, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestMaxConnsPerIP(t *testing.T) {
+	s, _ := startTestService(t, WithMaxConnsPerIP(2))
+	addr := s.listener.Addr().String()
+	dial := func() net.Conn {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+	first, second := dial(), dial()
+	for _, conn := range []net.Conn{first, second} {
+		if status, err := getHealth(conn); err != nil || status != http.StatusOK {
+			t.Fatalf("got status %d and error %v within the cap, want 200", status, err)
+		}
+	}
+	if _, err := getHealth(dial()); err == nil {
+		t.Error("got a response over the cap, want the connection refused")
+	}
+	// Closing a connection frees its slot once the server has noticed
+	_ = first.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if status, err := getHealth(dial()); err == nil && status == http.StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("connection still refused after another was closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
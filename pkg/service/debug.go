@@ -0,0 +1,92 @@
+package service
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/pprof"
+)
+
+// WithPprofMiddleware guards the pprof handlers enabled with WithPprof with the middleware, such as BasicAuth. The
+// handlers are registered on the mux ahead of the middleware chain, so they are otherwise open to anyone who can
+// reach the port.
+func WithPprofMiddleware(middleware ...Middleware) Option {
+	return func(o *Options) {
+		o.pprofMiddleware = append(o.pprofMiddleware, middleware...)
+	}
+}
+
+// BasicAuth returns middleware rejecting requests without the username and password with 401
+func BasicAuth(username, password string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, pass, ok := r.BasicAuth()
+			// Both are compared in full so that the time taken does not reveal which one differs
+			userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(username))
+			passMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(password))
+			if !ok || userMatch&passMatch != 1 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func registerPprof(mux *http.ServeMux, middleware []Middleware) {
+	// Register the profiling handlers
+	mux.Handle("/debug/pprof/", chain(http.HandlerFunc(pprof.Index), middleware))
+	mux.Handle("/debug/pprof/cmdline", chain(http.HandlerFunc(pprof.Cmdline), middleware))
+	mux.Handle("/debug/pprof/profile", chain(http.HandlerFunc(pprof.Profile), middleware))
+	mux.Handle("/debug/pprof/symbol", chain(http.HandlerFunc(pprof.Symbol), middleware))
+	mux.Handle("/debug/pprof/trace", chain(http.HandlerFunc(pprof.Trace), middleware))
+}
+
+// maxEchoBody is the largest request body echoed back by the echo endpoint
+const maxEchoBody = 1 << 20
+
+// WithEchoEndpoint serves an endpoint at the path that echoes the request method, headers and body back as JSON.
+// It only answers loopback clients and is disabled by default.
+func WithEchoEndpoint(path string) Option {
+	return func(o *Options) {
+		o.echoPath = path
+	}
+}
+
+type echoResponse struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Proto  string      `json:"proto"`
+	Host   string      `json:"host"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+func (s *service) handleEcho(w http.ResponseWriter, r *http.Request) {
+	// Echo the request back to loopback clients
+	if addr, err := parseHostAddr(r.RemoteAddr); err != nil || !addr.IsLoopback() {
+		s.Forbidden(w, r)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxEchoBody))
+	if err != nil {
+		s.BadRequest(w, r)
+		return
+	}
+	data, err := json.Marshal(echoResponse{
+		Method: r.Method,
+		URL:    r.URL.String(),
+		Proto:  r.Proto,
+		Host:   r.Host,
+		Header: r.Header,
+		Body:   string(body),
+	})
+	if err != nil {
+		s.InternalServerError(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPprof(t *testing.T) {
+	if code := serve(newTestService(WithPprof(true)).mux, http.MethodGet, "/debug/pprof/", nil).Code; code != http.StatusOK {
+		t.Errorf("pprof enabled: got status %d, want 200", code)
+	}
+	if code := serve(newTestService().mux, http.MethodGet, "/debug/pprof/", nil).Code; code != http.StatusNotFound {
+		t.Errorf("pprof disabled: got status %d, want 404", code)
+	}
+}
+
+func TestPprofMiddleware(t *testing.T) {
+	s := newTestService(WithPprof(true), WithPprofMiddleware(BasicAuth("admin", "secret")))
+	tests := []struct {
+		name     string
+		path     string
+		user     string
+		password string
+		want     int
+	}{
+		{"no credentials", "/debug/pprof/", "", "", http.StatusUnauthorized},
+		{"wrong password", "/debug/pprof/", "admin", "guess", http.StatusUnauthorized},
+		{"cmdline without credentials", "/debug/pprof/cmdline", "", "", http.StatusUnauthorized},
+		{"authorized", "/debug/pprof/", "admin", "secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.user != "" {
+				r.SetBasicAuth(tt.user, tt.password)
+			}
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got status %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("got no WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestExpvar(t *testing.T) {
+	w := serve(newTestService(WithExpvar(true)).mux, http.MethodGet, "/debug/vars", nil)
+	if w.Code != http.StatusOK || !json.Valid(w.Body.Bytes()) {
+		t.Errorf("expvar enabled: got status %d with body %q, want 200 with JSON", w.Code, w.Body.String())
+	}
+	if code := serve(newTestService().mux, http.MethodGet, "/debug/vars", nil).Code; code != http.StatusNotFound {
+		t.Errorf("expvar disabled: got status %d, want 404", code)
+	}
+}
+
+func TestEchoEndpoint(t *testing.T) {
+	s := newTestService(WithEchoEndpoint("/echo"))
+	tests := []struct {
+		name   string
+		remote string
+		want   int
+	}{
+		{"loopback", "127.0.0.1:5000", http.StatusOK},
+		{"remote", "192.0.2.1:5000", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/echo?a=1", strings.NewReader("hello"))
+			r.RemoteAddr = tt.remote
+			r.Header.Set("X-Test", "value")
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("got status %d, want %d", w.Code, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var echo echoResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &echo); err != nil {
+				t.Fatal(err)
+			}
+			if echo.Method != http.MethodPost || echo.URL != "/echo?a=1" || echo.Body != "hello" ||
+				echo.Header.Get("X-Test") != "value" {
+				t.Errorf("got echo %+v, want the request back", echo)
+			}
+		})
+	}
+	if code := serve(newTestService().mux, http.MethodPost, "/echo", nil).Code; code != http.StatusNotFound {
+		t.Errorf("echo disabled: got status %d, want 404", code)
+	}
+}
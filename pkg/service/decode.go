@@ -0,0 +1,42 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// DecodeJSONStream decodes a JSON array request body one element at a time, calling fn with each element so that
+// large bodies are never held in memory at once. Decoding stops at the first error returned by fn, which is returned
+// as is. A body that is not a JSON array is reported as ErrBind.
+func (r *Request) DecodeJSONStream(fn func(json.RawMessage) error) error {
+	var body io.Reader = r.httpRequest.Body
+	if r.bodyRead {
+		body = bytes.NewReader(r.body)
+	}
+	if body == nil {
+		return fmt.Errorf("%w: empty body", ErrBind)
+	}
+	decoder := json.NewDecoder(body)
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBind, err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("%w: expected a JSON array", ErrBind)
+	}
+	for decoder.More() {
+		var element json.RawMessage
+		if err := decoder.Decode(&element); err != nil {
+			return fmt.Errorf("%w: %v", ErrBind, err)
+		}
+		if err := fn(element); err != nil {
+			return err
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return fmt.Errorf("%w: %v", ErrBind, err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSONStream(t *testing.T) {
+	s := newTestService(WithMaxRequestBody(10 << 20))
+	s.POST("/bulk", func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		n := 0
+		err := request.DecodeJSONStream(func(element json.RawMessage) error {
+			var v struct{ N int }
+			if err := json.Unmarshal(element, &v); err != nil || v.N != n {
+				return fmt.Errorf("unexpected element %s", element)
+			}
+			n++
+			return nil
+		})
+		if err != nil {
+			w.WriteHeader(StatusFromError(err))
+		}
+		_, _ = w.Write([]byte(strconv.Itoa(n)))
+	})
+	const elements = 100000
+	body, writer := io.Pipe()
+	go func() {
+		_, _ = writer.Write([]byte("["))
+		for i := 0; i < elements; i++ {
+			if i > 0 {
+				_, _ = writer.Write([]byte(","))
+			}
+			_, _ = fmt.Fprintf(writer, `{"N":%d}`, i)
+		}
+		_, _ = writer.Write([]byte("]"))
+		_ = writer.Close()
+	}()
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bulk", body))
+	if w.Code != http.StatusOK || w.Body.String() != strconv.Itoa(elements) {
+		t.Errorf("got status %d after %s elements, want 200 after %d", w.Code, w.Body.String(), elements)
+	}
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"empty array", "[]", http.StatusOK},
+		{"not an array", `{"N":0}`, http.StatusBadRequest},
+		{"truncated", `[{"N":0},`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bulk", strings.NewReader(tt.body)))
+			if w.Code != tt.code {
+				t.Errorf("got status %d, want %d", w.Code, tt.code)
+			}
+		})
+	}
+}
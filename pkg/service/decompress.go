@@ -0,0 +1,68 @@
+package service
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// WithDecompressRequests transparently decompresses request bodies sent with Content-Encoding: gzip, so handlers
+// read the decompressed data. Bodies that are not valid gzip are rejected with 400.
+func WithDecompressRequests(decompressRequests bool) Option {
+	return func(o *Options) {
+		o.decompressRequests = decompressRequests
+	}
+}
+
+// WithMaxRequestBody caps the size of request bodies in bytes. Compressed bodies are capped on their decompressed
+// size. Reading past the cap fails with an *http.MaxBytesError, which StatusFromError maps to 413.
+func WithMaxRequestBody(maxRequestBody int64) Option {
+	return func(o *Options) {
+		o.maxRequestBody = maxRequestBody
+	}
+}
+
+// limitRequestBody counts, decompresses and caps the request body and makes it replayable by Request.Body,
+// reporting false when the request has been rejected
+func (s *service) limitRequestBody(w http.ResponseWriter, request *Request) bool {
+	r := request.HTTPRequest()
+	if r.Body == nil || r.Body == http.NoBody {
+		return true
+	}
+	request.bytesIn = &countingBody{ReadCloser: r.Body}
+	r.Body = request.bytesIn
+	if s.decompressRequests && isGzipEncoded(r.Header.Get("Content-Encoding")) {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			request.Logger().DebugContext(request.Context(), "invalid gzip request body", "error", err)
+			s.BadRequest(w, r)
+			return false
+		}
+		r.Body = &gzipBody{Reader: zr, body: r.Body}
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+	}
+	if s.maxRequestBody > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxRequestBody)
+	}
+	r.Body = &replayableBody{ReadCloser: r.Body}
+	return true
+}
+
+func isGzipEncoded(contentEncoding string) bool {
+	contentEncoding = strings.ToLower(strings.TrimSpace(contentEncoding))
+	return contentEncoding == "gzip" || contentEncoding == "x-gzip"
+}
+
+// gzipBody reads a gzip request body, closing the underlying body along with the decompressor
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	_ = b.Reader.Close()
+	return b.body.Close()
+}
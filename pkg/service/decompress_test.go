@@ -0,0 +1,60 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// gzipped returns the body compressed with gzip
+func gzipped(t *testing.T, body string) io.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestDecompressRequests(t *testing.T) {
+	s := newTestService(WithDecompressRequests(true), WithMaxRequestBody(100))
+	s.POST("/", func(w http.ResponseWriter, r *http.Request) {
+		var v map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+			w.WriteHeader(StatusFromError(err))
+			return
+		}
+		_, _ = w.Write([]byte(v["a"]))
+	})
+	tests := []struct {
+		name string
+		body io.Reader
+		code int
+		want string
+	}{
+		{"gzip", gzipped(t, `{"a":"b"}`), http.StatusOK, "b"},
+		{"decompressed over the limit", gzipped(t, `{"a":"`+strings.Repeat("x", 1000)+`"}`),
+			http.StatusRequestEntityTooLarge, ""},
+		{"invalid gzip", strings.NewReader("not gzip"), http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", tt.body)
+			r.Header.Set("Content-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, r)
+			if w.Code != tt.code || tt.want != "" && w.Body.String() != tt.want {
+				t.Errorf("got status %d with body %q, want %d with %q", w.Code, w.Body.String(), tt.code, tt.want)
+			}
+		})
+	}
+}
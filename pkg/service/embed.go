@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// embeddedFS is a file system served under a URL prefix
+type embeddedFS struct {
+	prefix string
+	fsys   fs.FS
+	spa    bool
+}
+
+// WithEmbeddedFS serves the files of the file system, such as an embed.FS narrowed with fs.Sub, under the URL prefix.
+// Directories are served their index.html. The files are served by a GET route, passing through the middleware chain.
+func WithEmbeddedFS(urlPrefix string, fsys fs.FS) Option {
+	return func(o *Options) {
+		o.embeddedFSs = append(o.embeddedFSs, embeddedFS{prefix: strings.TrimSuffix(urlPrefix, "/"), fsys: fsys})
+	}
+}
+
+// WithEmbeddedSPA serves the file system under the URL prefix as WithEmbeddedFS does, falling back to the root
+// index.html for paths naming no file so that a single page application can route them on the client
+func WithEmbeddedSPA(urlPrefix string, fsys fs.FS) Option {
+	return func(o *Options) {
+		o.embeddedFSs = append(o.embeddedFSs, embeddedFS{prefix: strings.TrimSuffix(urlPrefix, "/"), fsys: fsys, spa: true})
+	}
+}
+
+// handler serves the files of the file system for paths under the prefix
+func (e embeddedFS) handler() http.HandlerFunc {
+	files := http.FileServer(http.FS(e.fsys))
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, e.prefix), "/")
+		u := *r.URL
+		u.Path, u.RawPath = "/"+name, ""
+		if e.spa && name != "" {
+			if _, err := fs.Stat(e.fsys, path.Clean(name)); errors.Is(err, fs.ErrNotExist) {
+				u.Path = "/"
+			}
+		}
+		fr := r.WithContext(r.Context())
+		fr.URL = &u
+		files.ServeHTTP(w, fr)
+	}
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":    {Data: []byte("<h1>home</h1>")},
+		"assets/app.js": {Data: []byte("console.log(1)")},
+	}
+	tests := []struct {
+		name   string
+		option Option
+		path   string
+		code   int
+		body   string
+	}{
+		{"file", WithEmbeddedFS("/ui/", fsys), "/ui/assets/app.js", http.StatusOK, "console.log(1)"},
+		{"index", WithEmbeddedFS("/ui/", fsys), "/ui/", http.StatusOK, "<h1>home</h1>"},
+		{"missing", WithEmbeddedFS("/ui/", fsys), "/ui/settings", http.StatusNotFound, ""},
+		{"SPA file", WithEmbeddedSPA("/ui/", fsys), "/ui/assets/app.js", http.StatusOK, "console.log(1)"},
+		{"SPA fallback", WithEmbeddedSPA("/ui/", fsys), "/ui/settings/profile", http.StatusOK, "<h1>home</h1>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(tt.option)
+			w := serve(s.mux, http.MethodGet, tt.path, nil)
+			if w.Code != tt.code {
+				t.Fatalf("got status %d, want %d", w.Code, tt.code)
+			}
+			if tt.body != "" && strings.TrimSpace(w.Body.String()) != tt.body {
+				t.Errorf("got body %q, want %q", w.Body.String(), tt.body)
+			}
+		})
+	}
+}
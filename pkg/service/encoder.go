@@ -0,0 +1,30 @@
+package service
+
+import "encoding/json"
+
+// JSONEncoder encodes a value as JSON
+type JSONEncoder func(v interface{}) ([]byte, error)
+
+// WithJSONEncoder replaces json.Marshal for encoding response bodies with Request.JSONData, the WithJSON response
+// builder method, StreamJSONArray, DataHandler and the success response helpers, for example to indent the output
+// or disable HTML escaping. JSONData is not tied to a service and always uses json.Marshal.
+func WithJSONEncoder(jsonEncoder JSONEncoder) Option {
+	return func(o *Options) {
+		o.jsonEncoder = jsonEncoder
+	}
+}
+
+// encodeJSON encodes the value with the configured JSON encoder, falling back to json.Marshal
+func (o *Options) encodeJSON(v interface{}) ([]byte, error) {
+	if o == nil || o.jsonEncoder == nil {
+		return json.Marshal(v)
+	}
+	return o.jsonEncoder(v)
+}
+
+// JSONData returns a ResponseDataFunc that encodes the value with the JSON encoder of the service
+func (r *Request) JSONData(v interface{}) ResponseDataFunc {
+	return func() ([]byte, error) {
+		return r.options.encodeJSON(v)
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestJSONEncoder(t *testing.T) {
+	indent := func(v interface{}) ([]byte, error) {
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(v); err != nil {
+			return nil, err
+		}
+		return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+	}
+	value := map[string]string{"html": "<b>"}
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"default", nil, `{"html":"\u003cb\u003e"}`},
+		{"indented without HTML escaping", []Option{WithJSONEncoder(indent)}, "{\n  \"html\": \"<b>\"\n}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(tt.opts...)
+			s.GET("/builder", Handler(func(r *Request) *Response {
+				return r.ResponseBuilder().WithJSON(value).Build()
+			}).ServeHTTP)
+			s.GET("/data", DataHandler(func(r *Request) (interface{}, int, error) {
+				return value, http.StatusOK, nil
+			}).ServeHTTP)
+			s.GET("/ok", func(w http.ResponseWriter, r *http.Request) {
+				s.OK(w, r, value)
+			})
+			for _, path := range []string{"/builder", "/data", "/ok"} {
+				if got := serve(s.mux, http.MethodGet, path, nil).Body.String(); got != tt.want {
+					t.Errorf("%s: got %q, want %q", path, got, tt.want)
+				}
+			}
+		})
+	}
+}
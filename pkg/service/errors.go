@@ -0,0 +1,45 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrInvalidSignature is returned when a request signature does not match the request body
+	ErrInvalidSignature = errors.New("invalid request signature")
+	// ErrNoSessionStore is returned when sessions are used without a session store
+	ErrNoSessionStore = errors.New("no session store configured")
+	// ErrServiceStarted is returned when starting a service that is already running
+	ErrServiceStarted = errors.New("service already started")
+	// ErrServiceNotStarted is returned when stopping a service that is not running
+	ErrServiceNotStarted = errors.New("service not started")
+	// ErrServiceStopped is returned when starting a service that has been stopped, as its server cannot be reused
+	ErrServiceStopped = errors.New("service stopped")
+	// ErrRequestRejected is returned by request filters to reject a request with 403
+	ErrRequestRejected = errors.New("request rejected")
+	// ErrStreamCanceled is returned when a streamed response is cut short by its context
+	ErrStreamCanceled = errors.New("stream canceled")
+	// ErrListenerNotFile is returned when the listener of a service has no file descriptor to hand off
+	ErrListenerNotFile = errors.New("listener has no file descriptor")
+)
+
+// StatusFromError maps an error returned by the request helpers to an HTTP status code
+func StatusFromError(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.As(err, new(*http.MaxBytesError)):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrBind):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInvalidSignature):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrRequestRejected):
+		return http.StatusForbidden
+	case errors.As(err, new(*ValidationError)):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
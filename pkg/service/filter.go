@@ -0,0 +1,29 @@
+package service
+
+import "net/http"
+
+// RequestFilter inspects a request before it is routed, returning an error to reject it. The response status is
+// mapped from the error with StatusFromError, so filters blocking a request outright return ErrRequestRejected.
+type RequestFilter func(r *Request) error
+
+// WithRequestFilter appends filters run in order at the start of every request, before interceptors, routing and
+// middleware, so that unwanted requests such as those from blocked clients are turned away cheaply
+func WithRequestFilter(filters ...RequestFilter) Option {
+	return func(o *Options) {
+		o.requestFilters = append(o.requestFilters, filters...)
+	}
+}
+
+// filterRequest runs the filters, responding to the request and reporting false when one of them rejects it
+func (s *service) filterRequest(w http.ResponseWriter, request *Request) bool {
+	for _, filter := range s.requestFilters {
+		if err := filter(request); err != nil {
+			status := StatusFromError(err)
+			request.Logger().DebugContext(request.Context(), "request rejected by filter", "error", err,
+				"status", status)
+			s.ErrorResponse(w, request.HTTPRequest(), status, http.StatusText(status))
+			return false
+		}
+	}
+	return true
+}
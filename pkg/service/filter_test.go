@@ -0,0 +1,54 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRequestFilter(t *testing.T) {
+	var middlewareRun bool
+	blockBots := func(r *Request) error {
+		if strings.Contains(r.HTTPRequest().UserAgent(), "BadBot") {
+			return fmt.Errorf("blocked user agent: %w", ErrRequestRejected)
+		}
+		return nil
+	}
+	failing := func(r *Request) error {
+		if r.HTTPRequest().Header.Get("X-Fail") != "" {
+			return errors.New("filter failed")
+		}
+		return nil
+	}
+	s := newTestService(WithRequestFilter(blockBots, failing), WithMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			middlewareRun = true
+			next.ServeHTTP(w, r)
+		})
+	}))
+	s.GET("/", writeBody("ok"))
+	tests := []struct {
+		name       string
+		header     map[string]string
+		code       int
+		middleware bool
+	}{
+		{"allowed", map[string]string{"User-Agent": "Mozilla/5.0"}, http.StatusOK, true},
+		{"blocked", map[string]string{"User-Agent": "BadBot/1.0"}, http.StatusForbidden, false},
+		{"unmapped error", map[string]string{"X-Fail": "1"}, http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middlewareRun = false
+			w := serve(s.mux, http.MethodGet, "/", tt.header)
+			if w.Code != tt.code {
+				t.Errorf("got status %d, want %d", w.Code, tt.code)
+			}
+			if middlewareRun != tt.middleware {
+				t.Errorf("got middleware run %v, want %v", middlewareRun, tt.middleware)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+)
+
+// group registers routes under a shared path prefix and middleware
+type group struct {
+	service    *service
+	prefix     string
+	middleware []Middleware
+}
+
+func (s *service) Group(prefix string, middleware ...Middleware) Router {
+	return &group{service: s, prefix: prefix, middleware: middleware}
+}
+
+func (g *group) Group(prefix string, middleware ...Middleware) Router {
+	// Nested groups extend the prefix and run the outer group middleware first
+	return &group{
+		service:    g.service,
+		prefix:     joinPath(g.prefix, prefix),
+		middleware: append(append([]Middleware{}, g.middleware...), middleware...),
+	}
+}
+
+func (g *group) Route(method, pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	// The group middleware runs ahead of any middleware given for the route itself
+	opts = append([]RouteOption{WithRouteMiddleware(g.middleware...)}, opts...)
+	g.service.Route(method, joinPath(g.prefix, pattern), handler, opts...)
+}
+
+func (g *group) GET(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Route(http.MethodGet, pattern, handler, opts...)
+}
+
+func (g *group) POST(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Route(http.MethodPost, pattern, handler, opts...)
+}
+
+func (g *group) PUT(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Route(http.MethodPut, pattern, handler, opts...)
+}
+
+func (g *group) PATCH(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Route(http.MethodPatch, pattern, handler, opts...)
+}
+
+func (g *group) DELETE(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Route(http.MethodDelete, pattern, handler, opts...)
+}
+
+func (g *group) Handle(methods []string, pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	for _, method := range methods {
+		g.Route(method, pattern, handler, opts...)
+	}
+}
+
+func (g *group) Any(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	g.Route(anyMethod, pattern, handler, opts...)
+}
+
+// joinPath appends the pattern to the group prefix
+func joinPath(prefix, pattern string) string {
+	if pattern == "" {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(pattern, "/")
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	s := newTestService()
+	api := s.Group("/api/v1", traceMiddleware("api"))
+	api.GET("/status", writeBody("status"))
+	api.Group("/admin", traceMiddleware("admin")).GET("/users", writeBody("users"),
+		WithRouteMiddleware(traceMiddleware("route")))
+	tests := []struct {
+		path  string
+		body  string
+		trace string
+	}{
+		{"/api/v1/status", "status", "api"},
+		{"/api/v1/admin/users", "users", "api,admin,route"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := serve(s.mux, http.MethodGet, tt.path, nil)
+			if w.Code != http.StatusOK || w.Body.String() != tt.body {
+				t.Errorf("got status %d with body %q, want 200 with %q", w.Code, w.Body.String(), tt.body)
+			}
+			if trace := strings.Join(w.Header().Values("X-Trace"), ","); trace != tt.trace {
+				t.Errorf("got middleware %s, want %s", trace, tt.trace)
+			}
+		})
+	}
+	if code := serve(s.mux, http.MethodGet, "/admin/users", nil).Code; code != http.StatusNotFound {
+		t.Errorf("unprefixed path: got status %d, want 404", code)
+	}
+}
@@ -1,9 +1,152 @@
 package service
 
-import "net/http"
+import (
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
 
 func (s *service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.NotImplemented(w, r)
+	rw := NewTrackingWriter(w)
+	request := NewRequest(r.Context(), r, rw)
+	request.options = &s.Options
+	request.service = s
+	request.shutdown = s.streamShutdown
+	request.tracker = rw
+	if s.idGenerator != nil {
+		request.WithID(s.idGenerator())
+	}
+	request.attach()
+	if s.proxyHeaders {
+		s.applyProxyHeaders(request)
+	}
+	if s.checkURLLength(rw, request) && s.filterRequest(rw, request) && !s.intercept(request) {
+		s.serveRequest(rw, request)
+	}
+	s.logRequest(request, rw, time.Since(request.start))
+}
+
+// serveRequest routes the request and runs it through the middleware chain
+func (s *service) serveRequest(w *TrackingWriter, request *Request) {
+	if s.methodOverride {
+		overrideMethod(request.HTTPRequest())
+	}
+	if !s.limitRequestBody(w, request) {
+		return
+	}
+	s.matchRoute(request)
+	if s.responseBuffering {
+		buffered := newBufferedWriter(w, s.responseBufferLimit)
+		request.writer = buffered
+		s.handler.ServeHTTP(buffered, request.HTTPRequest())
+		buffered.finish()
+	} else {
+		s.handler.ServeHTTP(w, request.HTTPRequest())
+	}
+	s.finishResponse(w, request.HTTPRequest())
+}
+
+// matchRoute selects the handler for the request, answering OPTIONS with the methods allowed for the path and falling
+// back to the default handler, which responds with 404 unless set with WithDefaultHandler, for unrouted requests.
+// HEAD requests served by a GET route have their body discarded by the server.
+func (s *service) matchRoute(request *Request) {
+	r := request.HTTPRequest()
+	if s.trailingSlash == TrailingSlashStrip {
+		stripTrailingSlash(r.URL)
+	}
+	matched, params, allowed := s.routes.match(r.Method, r.URL.Path)
+	if matched == nil && len(allowed) == 0 {
+		if alternate, ok := s.alternatePath(r); ok {
+			if s.trailingSlash == TrailingSlashRedirect {
+				request.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					redirectToPath(w, r, alternate)
+				})
+				return
+			}
+			matched, params, allowed = s.routes.match(r.Method, alternate)
+		}
+	}
+	switch {
+	case matched != nil:
+		request.route = matched
+		request.params = params
+		request.handler = matched.handler
+	case len(allowed) > 0 && r.Method == http.MethodOptions:
+		request.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			w.WriteHeader(http.StatusNoContent)
+		})
+	case len(allowed) > 0:
+		request.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			s.MethodNotAllowed(w, r)
+		})
+	case s.defaultHandler != nil:
+		request.handler = s.defaultHandler
+	default:
+		request.handler = http.HandlerFunc(s.NotFound)
+	}
+}
+
+// dispatch calls the handler selected for the request once the middleware chain has run
+func (s *service) dispatch(w http.ResponseWriter, r *http.Request) {
+	request, ok := RequestFromContext(r.Context())
+	if !ok {
+		s.InternalServerError(w, r)
+		return
+	}
+	next := request.handler
+	handler := s.withTimeout(w, request, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Responses built from the request go through the writer the handler is given, past the timeout handler and
+		// the writers wrapped by middleware, rather than the one the request arrived with
+		request.writer = w
+		next.ServeHTTP(w, r)
+	}))
+	if s.idempotency != nil {
+		// Idempotent requests are checked once the global and route middleware have accepted them
+		handler = s.idempotency(handler)
+	}
+	if request.route != nil {
+		handler = chain(handler, request.route.middleware)
+	}
+	handler.ServeHTTP(w, r)
+}
+
+// finishResponse completes the response after the handler has returned
+func (s *service) finishResponse(w *TrackingWriter, r *http.Request) {
+	if s.emptyJSONBody != "" && w.written == 0 && r.Method != http.MethodHead && isJSONResponse(w, r) {
+		switch w.Status() {
+		case http.StatusNoContent, http.StatusNotModified:
+		default:
+			if w.Header().Get("Content-Type") == "" {
+				w.Header().Set("Content-Type", "application/json")
+			}
+			_, _ = w.Write([]byte(s.emptyJSONBody))
+		}
+	}
+}
+
+// isJSONResponse reports whether the response is JSON, either by its content type or, when none was set, by what
+// the client accepts
+func isJSONResponse(w http.ResponseWriter, r *http.Request) bool {
+	if contentType := w.Header().Get("Content-Type"); contentType != "" {
+		return isJSONMediaType(contentType)
+	}
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		if isJSONMediaType(accepted) {
+			return true
+		}
+	}
+	return false
+}
+
+func isJSONMediaType(value string) bool {
+	mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(value))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
 }
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"github.com/google/uuid"
+	"net/http"
+	"testing"
+)
+
+func TestEmptyJSONBody(t *testing.T) {
+	s := newTestService(WithEmptyJSONBody("{}"))
+	s.GET("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+	})
+	s.GET("/untyped", func(w http.ResponseWriter, r *http.Request) {})
+	s.GET("/no-content", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	s.GET("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+	})
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		want   string
+	}{
+		{"json content type", "/json", "", "{}"},
+		{"json accepted", "/untyped", "text/html, application/json", "{}"},
+		{"nothing accepted", "/untyped", "", ""},
+		{"no content", "/no-content", "", ""},
+		{"text", "/text", "application/json", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(s.mux, http.MethodGet, tt.path, map[string]string{"Accept": tt.accept})
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("got body %q, want %q", got, tt.want)
+			}
+		})
+	}
+	unset := newTestService()
+	unset.GET("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+	})
+	if got := serve(unset.mux, http.MethodGet, "/json", nil).Body.String(); got != "" {
+		t.Errorf("got body %q with the option unset, want none", got)
+	}
+}
+
+func TestIDGenerator(t *testing.T) {
+	var n byte
+	s := newTestService(WithIDGenerator(func() uuid.UUID {
+		n++
+		return uuid.UUID{15: n}
+	}))
+	s.GET("/id", func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		_, _ = w.Write([]byte(request.ID().String()))
+	})
+	for _, want := range []string{"00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-000000000002"} {
+		if got := serve(s.mux, http.MethodGet, "/id", nil).Body.String(); got != want {
+			t.Errorf("got ID %s, want %s", got, want)
+		}
+	}
+}
+
+func TestDefaultHandler(t *testing.T) {
+	if w := serve(newTestService().mux, http.MethodGet, "/unrouted", nil); w.Code != http.StatusNotFound {
+		t.Errorf("got status %d without a default handler, want 404", w.Code)
+	}
+	s := newTestService(WithDefaultHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})))
+	s.GET("/routed", writeBody("routed"))
+	if w := serve(s.mux, http.MethodGet, "/unrouted", nil); w.Code != http.StatusTeapot {
+		t.Errorf("got status %d from the default handler, want 418", w.Code)
+	}
+	if got := serve(s.mux, http.MethodGet, "/routed", nil).Body.String(); got != "routed" {
+		t.Errorf("got body %q for a routed path, want routed", got)
+	}
+}
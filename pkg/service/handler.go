@@ -0,0 +1,59 @@
+package service
+
+import "net/http"
+
+// Handler handles a request by returning the Response to send, or nil when it has written the response itself.
+// Handlers are registered through their ServeHTTP method, as in s.GET("/users", Handler(listUsers).ServeHTTP).
+type Handler func(r *Request) *Response
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	request, ok := RequestFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if response := h(request); response != nil {
+		// Send logs the errors it returns
+		_ = response.Send()
+	}
+}
+
+// DataHandler handles a request by returning the data to send as JSON with the status, or an error whose status is
+// mapped with StatusFromError. A zero status sends 200, and nil data sends no body. Handlers are registered through
+// their ServeHTTP method, as in s.GET("/users/{id}", DataHandler(getUser).ServeHTTP).
+type DataHandler func(r *Request) (interface{}, int, error)
+
+func (h DataHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	request, ok := RequestFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	data, status, err := h(request)
+	if err != nil {
+		status = StatusFromError(err)
+		if status >= http.StatusInternalServerError {
+			request.Logger().ErrorContext(request.Context(), "error handling request", "error", err)
+		}
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if data == nil {
+		w.WriteHeader(status)
+		return
+	}
+	body, err := request.options.encodeJSON(data)
+	if err != nil {
+		request.Logger().ErrorContext(request.Context(), "error encoding response body", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
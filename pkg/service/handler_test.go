@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	s := newTestService()
+	s.GET("/built", Handler(func(r *Request) *Response {
+		return r.ResponseBuilder().WithStatus(http.StatusCreated).WithHeader("X-Built", "true").
+			WithBody([]byte("built")).Build()
+	}).ServeHTTP)
+	s.GET("/direct", Handler(func(r *Request) *Response {
+		_, _ = r.Writer().Write([]byte("direct"))
+		return nil
+	}).ServeHTTP)
+	tests := []struct {
+		path   string
+		code   int
+		body   string
+		header string
+	}{
+		{"/built", http.StatusCreated, "built", "true"},
+		{"/direct", http.StatusOK, "direct", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := serve(s.mux, http.MethodGet, tt.path, nil)
+			if w.Code != tt.code || w.Body.String() != tt.body || w.Header().Get("X-Built") != tt.header {
+				t.Errorf("got status %d with body %q, want %d with %q", w.Code, w.Body.String(), tt.code, tt.body)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	Handler(func(r *Request) *Response { return nil }).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("outside a service: got status %d, want 500", w.Code)
+	}
+}
+
+func TestDataHandler(t *testing.T) {
+	type user struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	s := newTestService()
+	s.GET("/users/{id}", DataHandler(func(r *Request) (interface{}, int, error) {
+		return user{ID: r.PathValue("id"), Name: "alice"}, 0, nil
+	}).ServeHTTP)
+	s.POST("/users", DataHandler(func(r *Request) (interface{}, int, error) {
+		return user{ID: "8"}, http.StatusCreated, nil
+	}).ServeHTTP)
+	s.DELETE("/users/{id}", DataHandler(func(r *Request) (interface{}, int, error) {
+		return nil, http.StatusNoContent, nil
+	}).ServeHTTP)
+	s.GET("/forbidden", DataHandler(func(r *Request) (interface{}, int, error) {
+		return nil, 0, fmt.Errorf("listing users: %w", ErrRequestRejected)
+	}).ServeHTTP)
+	s.GET("/failing", DataHandler(func(r *Request) (interface{}, int, error) {
+		return user{}, http.StatusOK, errors.New("database unavailable")
+	}).ServeHTTP)
+	tests := []struct {
+		method      string
+		path        string
+		code        int
+		contentType string
+		body        string
+	}{
+		{http.MethodGet, "/users/7", http.StatusOK, "application/json", `{"id":"7","name":"alice"}`},
+		{http.MethodPost, "/users", http.StatusCreated, "application/json", `{"id":"8","name":""}`},
+		{http.MethodDelete, "/users/7", http.StatusNoContent, "", ""},
+		{http.MethodGet, "/forbidden", http.StatusForbidden, "text/plain; charset=utf-8", "Forbidden"},
+		{http.MethodGet, "/failing", http.StatusInternalServerError, "text/plain; charset=utf-8", "Internal Server Error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := serve(s.mux, tt.method, tt.path, nil)
+			if w.Code != tt.code {
+				t.Errorf("got status %d, want %d", w.Code, tt.code)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("got Content-Type %q, want %q", got, tt.contentType)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tt.body {
+				t.Errorf("got body %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
+
+// routeHeaderWriter sets a header as the response is started, standing in for middleware wrapping the writer
+type routeHeaderWriter struct {
+	http.ResponseWriter
+}
+
+func (w routeHeaderWriter) WriteHeader(status int) {
+	w.Header().Set("X-Wrapped", "yes")
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func TestRequestWriter(t *testing.T) {
+	wrap := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(routeHeaderWriter{w}, r)
+		})
+	}
+	send := func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		request.AddWarning(199, "built from the request")
+		_ = request.ResponseBuilder().WithStatus(http.StatusCreated).WithText("created").Build().Send()
+	}
+	t.Run("route middleware and handler timeout", func(t *testing.T) {
+		s := newTestService(WithHandlerTimeout(time.Second))
+		s.GET("/", send, WithRouteMiddleware(wrap))
+		w := serve(s.mux, http.MethodGet, "/", nil)
+		if w.Code != http.StatusCreated || w.Body.String() != "created" {
+			t.Fatalf("got status %d with body %q, want 201 created", w.Code, w.Body.String())
+		}
+		if w.Header().Get("X-Wrapped") != "yes" || w.Header().Get("Warning") == "" {
+			t.Errorf("got headers %v, want the response to pass through the route middleware", w.Header())
+		}
+	})
+	t.Run("timed out", func(t *testing.T) {
+		sent, release := make(chan error, 1), make(chan struct{})
+		s := newTestService(WithHandlerTimeout(10 * time.Millisecond))
+		s.GET("/", func(w http.ResponseWriter, r *http.Request) {
+			request, _ := RequestFromContext(r.Context())
+			<-release
+			sent <- request.ResponseBuilder().WithStatus(http.StatusOK).WithText("late").Build().Send()
+		})
+		w := serve(s.mux, http.MethodGet, "/", nil)
+		close(release)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("got status %d, want the timeout handler's 503", w.Code)
+		}
+		if err := <-sent; !errors.Is(err, http.ErrHandlerTimeout) {
+			t.Errorf("got %v sending after the timeout, want %v", err, http.ErrHandlerTimeout)
+		}
+		if strings.Contains(w.Body.String(), "late") {
+			t.Errorf("got body %q, want the late response dropped", w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,54 @@
+//go:build unix
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// ListenerFDEnv names the environment variable through which a restarting service passes the descriptor of its
+// listener to the process replacing it
+const ListenerFDEnv = "LISTEN_FD"
+
+// ListenerFile returns a duplicate of the file descriptor of the listener the running service accepts connections
+// on, for hand-off to a new process during a restart. The new process is started with the file in
+// exec.Cmd.ExtraFiles, which becomes descriptor 3 plus its index, and ListenerFDEnv set to that descriptor; once it
+// is serving, this service is stopped and the socket keeps accepting connections in the new process without a gap.
+// The caller closes the returned file.
+func (s *service) ListenerFile() (*os.File, error) {
+	s.mu.Lock()
+	listener := s.serving
+	s.mu.Unlock()
+	if listener == nil {
+		return nil, ErrServiceNotStarted
+	}
+	filer, ok := listener.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrListenerNotFile, listener)
+	}
+	return filer.File()
+}
+
+// InheritedListener returns the listener handed off by the process this one replaces, taken from the descriptor
+// named by ListenerFDEnv, for use with WithListener. It reports false when no listener was handed off.
+func InheritedListener() (net.Listener, bool, error) {
+	value, ok := os.LookupEnv(ListenerFDEnv)
+	if !ok {
+		return nil, false, nil
+	}
+	fd, err := strconv.Atoi(value)
+	if err != nil || fd < 0 {
+		return nil, false, fmt.Errorf("invalid %s %q", ListenerFDEnv, value)
+	}
+	file := os.NewFile(uintptr(fd), "listener")
+	// The listener holds its own duplicate of the descriptor
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, false, err
+	}
+	return listener, true, nil
+}
@@ -0,0 +1,22 @@
+//go:build !unix
+
+package service
+
+import (
+	"net"
+	"os"
+)
+
+// ListenerFDEnv names the environment variable through which a restarting service passes the descriptor of its
+// listener to the process replacing it
+const ListenerFDEnv = "LISTEN_FD"
+
+// ListenerFile is not supported outside Unix systems
+func (s *service) ListenerFile() (*os.File, error) {
+	return nil, ErrListenerNotFile
+}
+
+// InheritedListener is not supported outside Unix systems, where it reports that no listener was handed off
+func InheritedListener() (net.Listener, bool, error) {
+	return nil, false, nil
+}
@@ -0,0 +1,95 @@
+//go:build unix
+
+package service
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// handoffChildEnv marks the test binary run as the process taking over the listener
+const handoffChildEnv = "HANDOFF_CHILD"
+
+// whoHandler responds with the name of the process serving the request
+func whoHandler(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(name))
+	}
+}
+
+func TestListenerHandoff(t *testing.T) {
+	s, _ := startTestService(t)
+	s.GET("/who", whoHandler("parent"))
+	get := func() string {
+		client := &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{DisableKeepAlives: true}}
+		resp, err := client.Get(s.testURL("/who"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	if got := get(); got != "parent" {
+		t.Fatalf("got %q, want parent", got)
+	}
+	file, err := s.ListenerFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestListenerHandoffChild$")
+	cmd.Env = append(os.Environ(), handoffChildEnv+"=1", ListenerFDEnv+"=3")
+	// The first extra file becomes descriptor 3 in the child
+	cmd.ExtraFiles = []*os.File{file}
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		// Closing its input tells the child to stop
+		_ = stdin.Close()
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("child: %v", err)
+		}
+	}()
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	// Connections queue on the shared socket until the child accepts them, so none are refused
+	if got := get(); got != "child" {
+		t.Errorf("got %q after the hand-off, want child", got)
+	}
+}
+
+// TestListenerHandoffChild serves on the inherited listener when run by TestListenerHandoff
+func TestListenerHandoffChild(t *testing.T) {
+	if os.Getenv(handoffChildEnv) != "1" {
+		t.Skip("run by TestListenerHandoff")
+	}
+	listener, ok, err := InheritedListener()
+	if err != nil || !ok {
+		t.Fatalf("got inherited listener %v, error %v", ok, err)
+	}
+	s := newTestService(WithListener(listener))
+	s.GET("/who", whoHandler("child"))
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.Start()
+	}()
+	_, _ = io.Copy(io.Discard, os.Stdin)
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+}
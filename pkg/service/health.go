@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// Ping checks that the service is accepting connections by requesting its health endpoint over loopback
+func (s *service) Ping(ctx context.Context) error {
+	if !s.started.Load() {
+		return ErrServiceNotStarted
+	}
+	if s.disableHealthHandler {
+		return errors.New("ping requires the health handler")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.loopbackURL("/health"), nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{
+		Timeout: s.requestTimeout,
+		Transport: &http.Transport{
+			// The service is reached over loopback, which need not match its certificate
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true, ClientSessionCache: s.clientSessions},
+		},
+	}
+	defer client.CloseIdleConnections()
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check returned %s", resp.Status)
+	}
+	return nil
+}
+
+// clientSessionCache returns the TLS session cache for connections the service makes itself, or nil when there is
+// none. It is read from the server configuration before serving, as the server completes its TLS configuration when
+// it starts.
+func clientSessionCache(srv *http.Server) tls.ClientSessionCache {
+	if srv.TLSConfig == nil {
+		return nil
+	}
+	return srv.TLSConfig.ClientSessionCache
+}
+
+// loopbackURL returns the URL of the path on the service as reached from the local host
+func (s *service) loopbackURL(path string) string {
+	host, port := s.hostname, s.port
+	if addr, ok := s.listenerAddr(); ok {
+		host, port = addr.IP.String(), addr.Port
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	scheme := "http"
+	if s.requireTLS {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port)) + path
+}
+
+// handleLive reports that the process is running, for use as a liveness probe
+func handleLive(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("OK"))
+}
+
+// handleReady reports whether the service should receive traffic, for use as a readiness probe. It fails once the
+// service has begun shutting down, until the readiness gate is released, and while a readiness check fails.
+func (s *service) handleReady(w http.ResponseWriter, r *http.Request) {
+	if s.draining.Load() || !s.ready() {
+		s.ServiceUnavailable(w, r)
+		return
+	}
+	if failed := s.failedReadinessChecks(r.Context()); len(failed) > 0 {
+		for name, err := range failed {
+			slog.WarnContext(r.Context(), "readiness check failed", "check", name, "error", err)
+		}
+		s.ServiceUnavailable(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("OK"))
+}
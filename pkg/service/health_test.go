@@ -0,0 +1,21 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPing(t *testing.T) {
+	if err := newTestService().Ping(context.Background()); !errors.Is(err, ErrServiceNotStarted) {
+		t.Errorf("before start: got %v, want %v", err, ErrServiceNotStarted)
+	}
+	s, _ := startTestService(t)
+	if err := s.Ping(context.Background()); err != nil {
+		t.Errorf("while serving: got %v, want nil", err)
+	}
+	disabled, _ := startTestService(t, WithDisableHealthHandler(true))
+	if err := disabled.Ping(context.Background()); err == nil {
+		t.Error("without the health handler: got nil, want an error")
+	}
+}
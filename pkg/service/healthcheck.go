@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHealthCheckTimeout is the time a readiness check may take before it is reported as failed
+	DefaultHealthCheckTimeout = 2 * time.Second
+	// DefaultHealthCheckCacheTTL is the time a passing readiness check is trusted before it is run again
+	DefaultHealthCheckCacheTTL = 5 * time.Second
+)
+
+// ErrHealthCheckTimeout is reported for a readiness check that did not complete within the health check timeout
+var ErrHealthCheckTimeout = errors.New("health check timed out")
+
+// HealthCheck checks a dependency of the service, returning an error when it is unavailable
+type HealthCheck func(ctx context.Context) error
+
+// WithReadinessCheck adds a check to the /readyz readiness probe, which fails while any check does. Checks run
+// concurrently, each bounded by the health check timeout, and passing results are reused for the cache TTL so that
+// frequent probes do not load the dependency. Failing checks are run again on the next probe.
+func WithReadinessCheck(name string, check HealthCheck) Option {
+	return func(o *Options) {
+		o.readinessChecks = append(o.readinessChecks, &healthCheck{name: name, check: check})
+	}
+}
+
+// WithHealthCheckTimeout sets the time each readiness check may take, which defaults to DefaultHealthCheckTimeout
+func WithHealthCheckTimeout(healthCheckTimeout time.Duration) Option {
+	return func(o *Options) {
+		o.healthCheckTimeout = healthCheckTimeout
+	}
+}
+
+// WithHealthCheckCacheTTL sets how long a passing readiness check is reused, which defaults to
+// DefaultHealthCheckCacheTTL. A zero TTL runs the checks on every probe.
+func WithHealthCheckCacheTTL(healthCheckCacheTTL time.Duration) Option {
+	return func(o *Options) {
+		o.healthCheckCacheTTL = healthCheckCacheTTL
+	}
+}
+
+// healthCheck is a registered readiness check with its last result
+type healthCheck struct {
+	name      string
+	check     HealthCheck
+	mu        sync.Mutex
+	passed    bool
+	err       error
+	checkedAt time.Time
+	// running is closed once the check in progress completes, so that probes arriving meanwhile wait on it rather
+	// than running the check again
+	running chan struct{}
+}
+
+// run returns the result of the check, from the cache while a pass is fresh. A check outliving the timeout is left
+// to complete in the background, and its result is used by later probes.
+func (c *healthCheck) run(ctx context.Context, timeout, ttl time.Duration) error {
+	c.mu.Lock()
+	if c.passed && clk.Now().Sub(c.checkedAt) < ttl {
+		c.mu.Unlock()
+		return nil
+	}
+	if c.running == nil {
+		done := make(chan struct{})
+		c.running = done
+		go func() {
+			checkCtx, cancel := context.WithTimeout(context.Background(), timeout)
+			err := c.check(checkCtx)
+			cancel()
+			c.mu.Lock()
+			c.passed, c.err, c.checkedAt = err == nil, err, clk.Now()
+			c.running = nil
+			c.mu.Unlock()
+			close(done)
+		}()
+	}
+	running := c.running
+	c.mu.Unlock()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-running:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.err
+	case <-timer.C:
+		return ErrHealthCheckTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// failedReadinessChecks runs the readiness checks concurrently, returning the errors of those failing by name
+func (s *service) failedReadinessChecks(ctx context.Context) map[string]error {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failed := make(map[string]error)
+	for _, c := range s.readinessChecks {
+		wg.Add(1)
+		go func(c *healthCheck) {
+			defer wg.Done()
+			if err := c.run(ctx, s.healthCheckTimeout, s.healthCheckCacheTTL); err != nil {
+				mu.Lock()
+				failed[c.name] = err
+				mu.Unlock()
+			}
+		}(c)
+	}
+	wg.Wait()
+	return failed
+}
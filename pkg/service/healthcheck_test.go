@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadinessCheckTimeout(t *testing.T) {
+	release := make(chan struct{})
+	s := newTestService(WithHealthCheckTimeout(50*time.Millisecond),
+		WithReadinessCheck("slow", func(ctx context.Context) error {
+			<-release
+			return nil
+		}))
+	start := time.Now()
+	if code := serve(s.mux, http.MethodGet, "/readyz", nil).Code; code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want 503", code)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("probe took %v, want it bounded by the check timeout", elapsed)
+	}
+	// The check completes in the background and its pass is used by later probes
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for serve(s.mux, http.MethodGet, "/readyz", nil).Code != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("the check completing in the background was not used by later probes")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReadinessCheckCache(t *testing.T) {
+	fake := useFakeClock(t)
+	var calls atomic.Int32
+	var failing atomic.Bool
+	s := newTestService(WithHealthCheckCacheTTL(5*time.Second),
+		WithReadinessCheck("dependency", func(ctx context.Context) error {
+			calls.Add(1)
+			if failing.Load() {
+				return errors.New("unavailable")
+			}
+			return nil
+		}))
+	probe := func() int {
+		return serve(s.mux, http.MethodGet, "/readyz", nil).Code
+	}
+	tests := []struct {
+		name    string
+		advance time.Duration
+		failing bool
+		code    int
+		calls   int32
+	}{
+		{"first probe", 0, false, http.StatusOK, 1},
+		{"cached pass", time.Second, true, http.StatusOK, 1},
+		{"expired pass", 5 * time.Second, true, http.StatusServiceUnavailable, 2},
+		{"failure retried", 0, true, http.StatusServiceUnavailable, 3},
+		{"recovered", 0, false, http.StatusOK, 4},
+	}
+	for _, tt := range tests {
+		fake.Advance(tt.advance)
+		failing.Store(tt.failing)
+		if code := probe(); code != tt.code {
+			t.Errorf("%s: got status %d, want %d", tt.name, code, tt.code)
+		}
+		if n := calls.Load(); n != tt.calls {
+			t.Errorf("%s: got %d checks run, want %d", tt.name, n, tt.calls)
+		}
+	}
+}
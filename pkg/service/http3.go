@@ -0,0 +1,84 @@
+package service
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// ErrHTTP3Unsupported is returned when starting a service with HTTP/3 enabled that was built without the http3 tag
+var ErrHTTP3Unsupported = errors.New("HTTP/3 support requires building with -tags http3")
+
+// http3Server serves HTTP/3 over QUIC alongside the TCP server
+type http3Server interface {
+	ListenAndServe() error
+	Close() error
+}
+
+// newHTTP3Server creates the HTTP/3 server. It is only set when built with the http3 tag, which keeps the QUIC
+// implementation out of binaries that do not use it.
+var newHTTP3Server func(addr string, handler http.Handler, tlsConfig *tls.Config) http3Server
+
+// WithHTTP3 serves HTTP/3 on the UDP port matching the TCP port, advertising it to clients through the Alt-Svc
+// header. It requires TLS and building with -tags http3; Start fails with ErrHTTP3Unsupported otherwise.
+func WithHTTP3(http3 bool) Option {
+	return func(o *Options) {
+		o.http3 = http3
+	}
+}
+
+// http3Addr returns the address the HTTP/3 server listens on, matching the TCP server
+func (o Options) http3Addr() string {
+	if addr, ok := o.listenerAddr(); ok {
+		return addr.String()
+	}
+	return o.hostAddr()
+}
+
+// startHTTP3 starts serving HTTP/3 in the background
+func (s *service) startHTTP3() error {
+	if !s.requireTLS {
+		return errors.New("HTTP/3 requires TLS")
+	}
+	if newHTTP3Server == nil {
+		return ErrHTTP3Unsupported
+	}
+	s.h3 = newHTTP3Server(s.http3Addr(), s.mux, s.srv.TLSConfig)
+	go func(h3 http3Server) {
+		if err := h3.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("error serving HTTP/3", "error", err)
+		}
+	}(s.h3)
+	return nil
+}
+
+// stopHTTP3 closes the HTTP/3 server if one is running
+func (s *service) stopHTTP3() error {
+	if s.h3 == nil {
+		return nil
+	}
+	err := s.h3.Close()
+	s.h3 = nil
+	return err
+}
+
+// altSvcMiddleware advertises the HTTP/3 endpoint on the port
+func altSvcMiddleware(port int) Middleware {
+	value := fmt.Sprintf(`h3=":%d"; ma=86400`, port)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Alt-Svc", value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// http3Port returns the port advertised for HTTP/3
+func (o Options) http3Port() int {
+	if addr, ok := o.listenerAddr(); ok {
+		return addr.Port
+	}
+	return o.port
+}
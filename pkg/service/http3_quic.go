@@ -0,0 +1,15 @@
+//go:build http3
+
+package service
+
+import (
+	"crypto/tls"
+	"github.com/quic-go/quic-go/http3"
+	"net/http"
+)
+
+func init() {
+	newHTTP3Server = func(addr string, handler http.Handler, tlsConfig *tls.Config) http3Server {
+		return &http3.Server{Addr: addr, Handler: handler, TLSConfig: tlsConfig}
+	}
+}
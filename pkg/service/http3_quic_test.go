@@ -0,0 +1,33 @@
+//go:build http3
+
+package service
+
+import (
+	"crypto/tls"
+	"github.com/quic-go/quic-go/http3"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestHTTP3(t *testing.T) {
+	certFile, keyFile, _ := writeTestCert(t, "localhost")
+	s, _ := startTestService(t, WithRequireTLS(true), WithCertFile(certFile), WithKeyFile(keyFile), WithHTTP3(true))
+	s.GET("/proto", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	})
+	transport := &http3.RoundTripper{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	defer transport.Close()
+	resp, err := (&http.Client{Transport: transport}).Get("https://" + s.listener.Addr().String() + "/proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "HTTP/3.0" {
+		t.Errorf("got protocol %q, want HTTP/3.0", body)
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestAltSvc(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	certFile, keyFile, _ := writeTestCert(t, "localhost")
+	s := newTestService(WithListener(listener), WithRequireTLS(true), WithCertFile(certFile), WithKeyFile(keyFile),
+		WithHTTP3(true))
+	s.GET("/", writeBody("ok"))
+	port := listener.Addr().(*net.TCPAddr).Port
+	want := `h3=":` + strconv.Itoa(port) + `"; ma=86400`
+	if got := serve(s.mux, http.MethodGet, "/", nil).Header().Get("Alt-Svc"); got != want {
+		t.Errorf("got Alt-Svc %q, want %q", got, want)
+	}
+	plain := newTestService()
+	plain.GET("/", writeBody("ok"))
+	if got := serve(plain.mux, http.MethodGet, "/", nil).Header().Get("Alt-Svc"); got != "" {
+		t.Errorf("got Alt-Svc %q without HTTP/3, want none", got)
+	}
+}
+
+func TestHTTP3Unsupported(t *testing.T) {
+	if newHTTP3Server != nil {
+		t.Skip("built with the http3 tag")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	certFile, keyFile, _ := writeTestCert(t, "localhost")
+	s := newTestService(WithListener(listener), WithRequireTLS(true), WithCertFile(certFile), WithKeyFile(keyFile),
+		WithHTTP3(true))
+	if err := s.Start(); !errors.Is(err, ErrHTTP3Unsupported) {
+		t.Errorf("got %v, want %v", err, ErrHTTP3Unsupported)
+	}
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client chosen key identifying retries of the same request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from the idempotency store
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotentBody is the largest response body stored for replay
+	maxIdempotentBody = 1 << 20
+)
+
+// CachedResponse is a response stored for replay to retries of an idempotent request
+type CachedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	// RequestHash is the SHA-256 hash of the body of the request the response was sent for
+	RequestHash []byte
+}
+
+// IdempotencyStore stores responses by idempotency key
+type IdempotencyStore interface {
+	// Get returns the response stored under the key, reporting false when there is none
+	Get(ctx context.Context, key string) (*CachedResponse, bool, error)
+	// Set stores the response under the key for the ttl
+	Set(ctx context.Context, key string, response *CachedResponse, ttl time.Duration) error
+}
+
+// IdempotencyScope returns the identity of the client sending a request, so that idempotency keys chosen by
+// different clients do not collide
+type IdempotencyScope func(r *Request) string
+
+// WithIdempotency replays responses to POST and PATCH requests carrying an Idempotency-Key header. The first request
+// with a key runs and its response is stored for the ttl; retries with the same key, method and path from the same
+// client are sent the stored response without running the handler again. Retries arriving while the first request
+// is still running are rejected with 409, retries with a different body with 422, and server errors are not stored
+// so that they can be retried. Requests are checked after the global and route middleware have run, so that requests
+// they reject or rewrite are not stored.
+func WithIdempotency(store IdempotencyStore, ttl time.Duration) Option {
+	return func(o *Options) {
+		o.idempotencyStore = store
+		o.idempotencyTTL = ttl
+	}
+}
+
+// WithIdempotencyScope sets how the client sending a request is identified for WithIdempotency. By default clients
+// are told apart by their Authorization header, or by their address when they send none.
+func WithIdempotencyScope(scope IdempotencyScope) Option {
+	return func(o *Options) {
+		o.idempotencyScope = scope
+	}
+}
+
+// defaultIdempotencyScope identifies the client by its credentials, or by its address without any
+func defaultIdempotencyScope(r *Request) string {
+	if authorization := r.HTTPRequest().Header.Get("Authorization"); authorization != "" {
+		return authorization
+	}
+	return r.ClientIP()
+}
+
+// idempotencyMiddleware replays stored responses for requests with an idempotency key
+func (s *service) idempotencyMiddleware() Middleware {
+	var mu sync.Mutex
+	inFlight := make(map[string]bool)
+	scope := s.idempotencyScope
+	if scope == nil {
+		scope = defaultIdempotencyScope
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+			if idempotencyKey == "" || r.Method != http.MethodPost && r.Method != http.MethodPatch {
+				next.ServeHTTP(w, r)
+				return
+			}
+			request, ok := RequestFromContext(r.Context())
+			if !ok {
+				s.InternalServerError(w, r)
+				return
+			}
+			body, err := request.Body()
+			if err != nil {
+				status := StatusFromError(err)
+				s.ErrorResponse(w, r, status, http.StatusText(status))
+				return
+			}
+			requestHash := sha256.Sum256(body)
+			// The client identity is hashed so that credentials are not kept in the store
+			client := sha256.Sum256([]byte(scope(request)))
+			key := r.Method + " " + r.URL.Path + " " + hex.EncodeToString(client[:]) + " " + idempotencyKey
+			// The key is held until the response is stored, so a retry either finds it running or finds its response
+			mu.Lock()
+			if inFlight[key] {
+				mu.Unlock()
+				s.Conflict(w, r)
+				return
+			}
+			inFlight[key] = true
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				delete(inFlight, key)
+				mu.Unlock()
+			}()
+			cached, ok, err := s.idempotencyStore.Get(r.Context(), key)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "error reading idempotency store", "error", err)
+				s.InternalServerError(w, r)
+				return
+			}
+			if ok {
+				if !bytes.Equal(cached.RequestHash, requestHash[:]) {
+					s.UnprocessableEntity(w, r)
+					return
+				}
+				replay(w, cached)
+				return
+			}
+			recorder := &recordingWriter{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+			if recorder.overflow || recorder.status >= http.StatusInternalServerError {
+				return
+			}
+			if recorder.status == 0 {
+				recorder.status = http.StatusOK
+				recorder.header = w.Header().Clone()
+			}
+			response := &CachedResponse{Status: recorder.status, Header: recorder.header, Body: recorder.body.Bytes(),
+				RequestHash: requestHash[:]}
+			if err := s.idempotencyStore.Set(r.Context(), key, response, s.idempotencyTTL); err != nil {
+				slog.ErrorContext(r.Context(), "error writing idempotency store", "error", err)
+			}
+		})
+	}
+}
+
+// replay writes a stored response
+func replay(w http.ResponseWriter, cached *CachedResponse) {
+	for name, values := range cached.Header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(cached.Status)
+	_, _ = w.Write(cached.Body)
+}
+
+// recordingWriter records the response passing through it
+type recordingWriter struct {
+	http.ResponseWriter
+	status   int
+	header   http.Header
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= 200 {
+		w.status = status
+		w.header = w.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+		w.header = w.Header().Clone()
+	}
+	if w.body.Len()+len(b) > maxIdempotentBody {
+		w.overflow = true
+		w.body.Reset()
+	}
+	if !w.overflow {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// MemoryIdempotencyStore keeps responses in memory. It suits a single instance; services running several instances
+// need a shared store.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	responses map[string]memoryIdempotencyEntry
+	lastSweep time.Time
+}
+
+type memoryIdempotencyEntry struct {
+	response *CachedResponse
+	expires  time.Time
+}
+
+// NewMemoryIdempotencyStore returns an empty MemoryIdempotencyStore
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{responses: make(map[string]memoryIdempotencyEntry)}
+}
+
+func (m *MemoryIdempotencyStore) Get(_ context.Context, key string) (*CachedResponse, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.responses[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if clk.Now().After(entry.expires) {
+		delete(m.responses, key)
+		return nil, false, nil
+	}
+	return entry.response, true, nil
+}
+
+func (m *MemoryIdempotencyStore) Set(_ context.Context, key string, response *CachedResponse, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := clk.Now()
+	// Expired entries are swept out at most once a minute
+	if now.Sub(m.lastSweep) > time.Minute {
+		for k, entry := range m.responses {
+			if now.After(entry.expires) {
+				delete(m.responses, k)
+			}
+		}
+		m.lastSweep = now
+	}
+	m.responses[key] = memoryIdempotencyEntry{response: response, expires: now.Add(ttl)}
+	return nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// post sends a POST request with the body and headers through the handler and returns the recorded response
+func post(h http.Handler, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	for name, value := range header {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotency(t *testing.T) {
+	var calls atomic.Int32
+	s := newTestService(WithIdempotency(NewMemoryIdempotencyStore(), time.Minute))
+	s.POST("/orders", func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("order " + strconv.Itoa(int(n))))
+	})
+	alice := map[string]string{IdempotencyKeyHeader: "k1", "Authorization": "Bearer alice"}
+	bob := map[string]string{IdempotencyKeyHeader: "k1", "Authorization": "Bearer bob"}
+	tests := []struct {
+		name   string
+		body   string
+		header map[string]string
+		code   int
+		want   string
+	}{
+		{"first", "item", alice, http.StatusCreated, "order 1"},
+		{"retry", "item", alice, http.StatusCreated, "order 1"},
+		{"other client", "item", bob, http.StatusCreated, "order 2"},
+		{"different body", "other", alice, http.StatusUnprocessableEntity, ""},
+		{"no key", "item", nil, http.StatusCreated, "order 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post(s.mux, "/orders", tt.body, tt.header)
+			if w.Code != tt.code {
+				t.Fatalf("got status %d, want %d", w.Code, tt.code)
+			}
+			if tt.want != "" && w.Body.String() != tt.want {
+				t.Errorf("got body %q, want %q", w.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	s := newTestService(WithIdempotency(NewMemoryIdempotencyStore(), time.Minute))
+	s.POST("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	header := map[string]string{IdempotencyKeyHeader: "k1"}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		post(s.mux, "/slow", "", header)
+	}()
+	<-started
+	if w := post(s.mux, "/slow", "", header); w.Code != http.StatusConflict {
+		t.Errorf("got status %d while the first request runs, want 409", w.Code)
+	}
+	close(release)
+	<-done
+}
+
+func TestIdempotencyAfterMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	// The middleware derives the key from another header, so the idempotency check must run after it
+	keyFromHeader := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := r.Header.Get("X-Request-Key"); key != "" {
+				r.Header.Set(IdempotencyKeyHeader, key)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	requireAuth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	s := newTestService(WithIdempotency(NewMemoryIdempotencyStore(), time.Minute), WithMiddleware(keyFromHeader))
+	s.POST("/orders", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("order " + strconv.Itoa(int(calls.Add(1)))))
+	}, WithRouteMiddleware(requireAuth))
+	if w := post(s.mux, "/orders", "", map[string]string{"X-Request-Key": "k1"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("got status %d without credentials, want 401", w.Code)
+	}
+	header := map[string]string{"X-Request-Key": "k1", "Authorization": "Bearer alice"}
+	for i := 0; i < 2; i++ {
+		if got := post(s.mux, "/orders", "", header).Body.String(); got != "order 1" {
+			t.Errorf("request %d: got body %q, want order 1", i+1, got)
+		}
+	}
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	var calls atomic.Int32
+	s := newTestService(WithIdempotency(NewMemoryIdempotencyStore(), time.Minute),
+		WithIdempotencyScope(func(r *Request) string { return r.HTTPRequest().Header.Get("X-Tenant") }))
+	s.POST("/orders", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("order " + strconv.Itoa(int(calls.Add(1)))))
+	})
+	tests := []struct {
+		tenant string
+		want   string
+	}{
+		{"a", "order 1"},
+		{"a", "order 1"},
+		{"b", "order 2"},
+	}
+	for _, tt := range tests {
+		header := map[string]string{IdempotencyKeyHeader: "k1", "X-Tenant": tt.tenant}
+		if got := post(s.mux, "/orders", "", header).Body.String(); got != tt.want {
+			t.Errorf("tenant %s: got body %q, want %q", tt.tenant, got, tt.want)
+		}
+	}
+}
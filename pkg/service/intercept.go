@@ -0,0 +1,22 @@
+package service
+
+// Interceptor inspects a request before it is routed. Returning true reports that the interceptor has written the
+// response itself, and the request is not dispatched any further.
+type Interceptor func(r *Request) (handled bool)
+
+// WithInterceptor appends interceptors run in order at the start of every request, before routing and middleware
+func WithInterceptor(interceptors ...Interceptor) Option {
+	return func(o *Options) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// intercept runs the interceptors, reporting whether one of them handled the request
+func (s *service) intercept(request *Request) bool {
+	for _, interceptor := range s.interceptors {
+		if interceptor(request) {
+			return true
+		}
+	}
+	return false
+}
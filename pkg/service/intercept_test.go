@@ -0,0 +1,52 @@
+package service
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestInterceptor(t *testing.T) {
+	var order []string
+	s := newTestService(
+		WithInterceptor(func(r *Request) bool {
+			order = append(order, "pass")
+			return false
+		}),
+		WithInterceptor(func(r *Request) bool {
+			order = append(order, "block")
+			if r.HTTPRequest().URL.Path != "/blocked" {
+				return false
+			}
+			r.Writer().WriteHeader(http.StatusForbidden)
+			return true
+		}),
+		WithMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, "middleware")
+				next.ServeHTTP(w, r)
+			})
+		}),
+	)
+	s.GET("/blocked", writeBody("blocked"))
+	s.GET("/allowed", writeBody("allowed"))
+	tests := []struct {
+		path  string
+		code  int
+		order []string
+	}{
+		{"/blocked", http.StatusForbidden, []string{"pass", "block"}},
+		{"/allowed", http.StatusOK, []string{"pass", "block", "middleware"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			order = nil
+			if code := serve(s.mux, http.MethodGet, tt.path, nil).Code; code != tt.code {
+				t.Errorf("got status %d, want %d", code, tt.code)
+			}
+			if !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("got order %v, want %v", order, tt.order)
+			}
+		})
+	}
+}
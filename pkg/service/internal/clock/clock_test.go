@@ -0,0 +1,35 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Unix(1000, 0)
+	fake := NewFake(start)
+	c := fake.After(time.Second)
+	fake.Advance(999 * time.Millisecond)
+	select {
+	case <-c:
+		t.Fatal("fired before the deadline")
+	default:
+	}
+	fake.Advance(time.Millisecond)
+	select {
+	case now := <-c:
+		if want := start.Add(time.Second); !now.Equal(want) {
+			t.Errorf("fired at %v, want %v", now, want)
+		}
+	default:
+		t.Fatal("did not fire at the deadline")
+	}
+	select {
+	case <-fake.After(0):
+	default:
+		t.Error("did not fire straight away for a zero duration")
+	}
+	if now := fake.Now(); !now.Equal(start.Add(time.Second)) {
+		t.Errorf("got now %v, want %v", now, start.Add(time.Second))
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// LifecycleState is the stage of its lifecycle a service is in, as reported by the /drainz endpoint
+type LifecycleState string
+
+const (
+	// LifecycleStarting is reported until the service is started and its readiness gate, if any, is released
+	LifecycleStarting LifecycleState = "starting"
+	// LifecycleReady is reported while the service is serving traffic
+	LifecycleReady LifecycleState = "ready"
+	// LifecycleDraining is reported from a shutdown signal or the start of Stop until in-flight requests have drained
+	LifecycleDraining LifecycleState = "draining"
+	// LifecycleStopped is reported once Stop has completed
+	LifecycleStopped LifecycleState = "stopped"
+)
+
+// lifecycleState returns the current stage of the lifecycle of the service
+func (s *service) lifecycleState() LifecycleState {
+	switch {
+	case s.stopped.Load():
+		return LifecycleStopped
+	case s.draining.Load():
+		return LifecycleDraining
+	case !s.started.Load() || !s.ready():
+		return LifecycleStarting
+	default:
+		return LifecycleReady
+	}
+}
+
+// handleDrain reports the lifecycle state of the service as JSON, such as {"state":"draining"}, for dashboards and
+// load balancers. It responds with 503 while the service is draining or stopped.
+func (s *service) handleDrain(w http.ResponseWriter, r *http.Request) {
+	state := s.lifecycleState()
+	data, err := json.Marshal(struct {
+		State LifecycleState `json:"state"`
+	}{State: state})
+	if err != nil {
+		s.InternalServerError(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if state == LifecycleDraining || state == LifecycleStopped {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// drainState returns the status and lifecycle state reported by /drainz, failing the test if it does not answer
+// promptly
+func drainState(t *testing.T, s *service) (int, LifecycleState) {
+	t.Helper()
+	type result struct {
+		code  int
+		state LifecycleState
+	}
+	results := make(chan result, 1)
+	go func() {
+		w := serve(s.mux, http.MethodGet, "/drainz", nil)
+		var body struct {
+			State LifecycleState `json:"state"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		results <- result{w.Code, body.State}
+	}()
+	select {
+	case r := <-results:
+		return r.code, r.state
+	case <-time.After(5 * time.Second):
+		t.Fatal("drainz did not answer")
+		return 0, ""
+	}
+}
+
+func TestLifecycleState(t *testing.T) {
+	gate, release := WithReadinessGate(false)
+	inFlight, finish := make(chan struct{}), make(chan struct{})
+	s, errc := startTestService(t, gate, WithShutdownTimeout(5*time.Second))
+	s.GET("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(inFlight)
+		<-finish
+	}, WithRouteTimeout(0))
+	if code, state := drainState(t, s); code != http.StatusOK || state != LifecycleStarting {
+		t.Errorf("before release: got %d %s, want 200 %s", code, state, LifecycleStarting)
+	}
+	release()
+	if code, state := drainState(t, s); code != http.StatusOK || state != LifecycleReady {
+		t.Errorf("after release: got %d %s, want 200 %s", code, state, LifecycleReady)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resp, err := http.Get(s.testURL("/slow"))
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-inFlight
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- s.Stop()
+	}()
+	// Stop holds the service lock while it drains the in-flight request, which must not block the endpoint
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		code, state := drainState(t, s)
+		if state == LifecycleDraining {
+			if code != http.StatusServiceUnavailable {
+				t.Errorf("while draining: got status %d, want 503", code)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %s while stopping, want %s", state, LifecycleDraining)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := s.Ping(context.Background()); !errors.Is(err, ErrServiceNotStarted) {
+		t.Errorf("ping while draining: got %v, want %v", err, ErrServiceNotStarted)
+	}
+	close(finish)
+	<-done
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+	<-errc
+	if code, state := drainState(t, s); code != http.StatusServiceUnavailable || state != LifecycleStopped {
+		t.Errorf("after stop: got %d %s, want 503 %s", code, state, LifecycleStopped)
+	}
+}
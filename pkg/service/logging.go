@@ -0,0 +1,38 @@
+package service
+
+import (
+	"log/slog"
+	"time"
+)
+
+// logRequest logs the completed request, at warn level when it exceeded the slow request or large response
+// thresholds and at debug level otherwise, and writes it to the access log
+func (s *service) logRequest(request *Request, w *TrackingWriter, elapsed time.Duration) {
+	s.writeAccessLog(request, w, elapsed)
+	r := request.HTTPRequest()
+	attrs := []any{
+		slog.String("request_id", request.ID().String()),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("client_ip", request.ClientIP()),
+		slog.String("route", request.RoutePattern()),
+		slog.Int("status", w.Status()),
+		slog.Int64("bytes", w.written),
+		slog.Duration("duration", elapsed),
+	}
+	if protocol := request.NegotiatedProtocol(); protocol != "" {
+		attrs = append(attrs, slog.String("alpn", protocol))
+	}
+	slow := s.slowRequestThreshold > 0 && elapsed > s.slowRequestThreshold
+	large := s.largeResponseThreshold > 0 && w.written > s.largeResponseThreshold
+	switch {
+	case slow && large:
+		slog.WarnContext(request.Context(), "slow request with large response", attrs...)
+	case slow:
+		slog.WarnContext(request.Context(), "slow request", attrs...)
+	case large:
+		slog.WarnContext(request.Context(), "large response", attrs...)
+	default:
+		slog.DebugContext(request.Context(), "request completed", attrs...)
+	}
+}
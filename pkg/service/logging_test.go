@@ -0,0 +1,67 @@
+package service
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a buffer safe for concurrent writes by loggers
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLogs routes the default logger to the returned buffer at debug level until the test ends
+func captureLogs(t *testing.T) *lockedBuffer {
+	t.Helper()
+	logs := &lockedBuffer{}
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+	})
+	return logs
+}
+
+func TestSlowRequestAndLargeResponseLogging(t *testing.T) {
+	s := newTestService(WithSlowRequestThreshold(5*time.Millisecond), WithLargeResponseThreshold(4))
+	s.GET("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+	})
+	s.GET("/large", writeBody("more than four bytes"))
+	s.GET("/fast", writeBody("ok"))
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/slow", `level=WARN msg="slow request"`},
+		{"/large", `level=WARN msg="large response"`},
+		{"/fast", `level=DEBUG msg="request completed"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			logs := captureLogs(t)
+			serve(s.mux, http.MethodGet, tt.path, nil)
+			if got := logs.String(); !strings.Contains(got, tt.want) || !strings.Contains(got, "route="+tt.path) {
+				t.Errorf("got logs %q, want %s with the route", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// RouteMetadata documents a route for the OpenAPI document and route introspection
+type RouteMetadata struct {
+	Summary     string   `json:"summary,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// RequestType is the type of the JSON request body
+	RequestType reflect.Type `json:"-"`
+	// ResponseType is the type of the JSON body of successful responses
+	ResponseType reflect.Type `json:"-"`
+}
+
+// RouteInfo describes a registered route
+type RouteInfo struct {
+	Method   string        `json:"method"`
+	Pattern  string        `json:"pattern"`
+	Metadata RouteMetadata `json:"metadata"`
+}
+
+// WithSummary sets the one line summary of the route
+func WithSummary(summary string) RouteOption {
+	return func(r *route) {
+		r.metadata.Summary = summary
+	}
+}
+
+// WithDescription sets the description of the route
+func WithDescription(description string) RouteOption {
+	return func(r *route) {
+		r.metadata.Description = description
+	}
+}
+
+// WithTags appends tags grouping the route with related routes
+func WithTags(tags ...string) RouteOption {
+	return func(r *route) {
+		r.metadata.Tags = append(r.metadata.Tags, tags...)
+	}
+}
+
+// WithRequestType sets the type of the JSON request body of the route to that of the example value, such as
+// CreateUser{}
+func WithRequestType(example interface{}) RouteOption {
+	return func(r *route) {
+		r.metadata.RequestType = reflect.TypeOf(example)
+	}
+}
+
+// WithResponseType sets the type of the JSON body of successful responses of the route to that of the example
+// value, such as UserList{}
+func WithResponseType(example interface{}) RouteOption {
+	return func(r *route) {
+		r.metadata.ResponseType = reflect.TypeOf(example)
+	}
+}
+
+// Routes returns the registered routes with their metadata, in order of registration
+func (s *service) Routes() []RouteInfo {
+	routes := s.routes.list()
+	infos := make([]RouteInfo, 0, len(routes))
+	for _, r := range routes {
+		infos = append(infos, RouteInfo{Method: r.method, Pattern: r.pattern, Metadata: r.metadata})
+	}
+	return infos
+}
+
+// WithRoutesEndpoint serves the registered routes and their metadata as JSON at the path, such as /routes. It is off
+// unless a path is set, and exposes the full surface of the service, so it is best kept to internal deployments.
+func WithRoutesEndpoint(path string) Option {
+	return func(o *Options) {
+		o.routesPath = path
+	}
+}
+
+// routeListing is a route as listed by the routes endpoint, with its request and response types named
+type routeListing struct {
+	RouteInfo
+	RequestType  string `json:"requestType,omitempty"`
+	ResponseType string `json:"responseType,omitempty"`
+}
+
+func (s *service) handleRoutes(w http.ResponseWriter, r *http.Request) {
+	routes := s.Routes()
+	listings := make([]routeListing, 0, len(routes))
+	for _, route := range routes {
+		listing := routeListing{RouteInfo: route}
+		if t := route.Metadata.RequestType; t != nil {
+			listing.RequestType = t.String()
+		}
+		if t := route.Metadata.ResponseType; t != nil {
+			listing.ResponseType = t.String()
+		}
+		listings = append(listings, listing)
+	}
+	data, err := json.Marshal(listings)
+	if err != nil {
+		s.InternalServerError(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// jsonSchema returns a JSON Schema describing the JSON encoding of values of the type. Types referring to themselves
+// are described as plain objects where they recur.
+func jsonSchema(t reflect.Type, seen map[reflect.Type]bool) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case seen[t]:
+		return map[string]interface{}{"type": "object"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Byte slices are encoded as base64 strings
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": jsonSchema(t.Elem(), seen)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchema(t.Elem(), seen)}
+	case reflect.Struct:
+		seen[t] = true
+		defer delete(seen, t)
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			switch name {
+			case "-":
+				continue
+			case "":
+				name = field.Name
+			}
+			properties[name] = jsonSchema(field.Type, seen)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	default:
+		return map[string]interface{}{}
+	}
+}
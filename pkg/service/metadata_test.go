@@ -0,0 +1,62 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type userList struct {
+	Users []string `json:"users"`
+}
+
+func TestRouteMetadata(t *testing.T) {
+	s := newTestService()
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	s.GET("/users", noop, WithSummary("List users"), WithDescription("Lists every user"), WithTags("users"),
+		WithResponseType(userList{}))
+	s.POST("/users", noop, WithRequestType(&userList{}), WithTags("users", "admin"))
+	want := []RouteInfo{
+		{Method: http.MethodGet, Pattern: "/users", Metadata: RouteMetadata{Summary: "List users",
+			Description: "Lists every user", Tags: []string{"users"}, ResponseType: reflect.TypeOf(userList{})}},
+		{Method: http.MethodPost, Pattern: "/users", Metadata: RouteMetadata{Tags: []string{"users", "admin"},
+			RequestType: reflect.TypeOf(&userList{})}},
+	}
+	if got := s.Routes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got routes %+v, want %+v", got, want)
+	}
+}
+
+func TestRoutesEndpoint(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	t.Run("enabled", func(t *testing.T) {
+		s := newTestService(WithRoutesEndpoint("/routes"))
+		s.GET("/users", noop, WithSummary("List users"), WithResponseType(userList{}))
+		w := serve(s.mux, http.MethodGet, "/routes", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("got status %d, want 200", w.Code)
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("got Content-Type %q, want application/json", got)
+		}
+		var got []routeListing
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		want := []routeListing{{
+			RouteInfo:    RouteInfo{Method: http.MethodGet, Pattern: "/users", Metadata: RouteMetadata{Summary: "List users"}},
+			ResponseType: "service.userList",
+		}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got routes %+v, want %+v", got, want)
+		}
+	})
+	t.Run("disabled", func(t *testing.T) {
+		s := newTestService()
+		s.GET("/users", noop)
+		if w := serve(s.mux, http.MethodGet, "/routes", nil); w.Code != http.StatusNotFound {
+			t.Errorf("got status %d, want 404", w.Code)
+		}
+	})
+}
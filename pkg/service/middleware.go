@@ -0,0 +1,72 @@
+package service
+
+import (
+	"net/http"
+)
+
+// Middleware wraps a handler with additional behaviour
+type Middleware func(next http.Handler) http.Handler
+
+// WithMiddleware appends middleware to the chain run for every routed request. Middleware runs in the order given,
+// after the route has been matched and before its handler is called.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(o *Options) {
+		o.middleware = append(o.middleware, middleware...)
+	}
+}
+
+// chain wraps the handler with the middleware so that the first middleware is the outermost
+func chain(handler http.Handler, middleware []Middleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// buildHandler assembles the built-in middleware enabled by the options ahead of the user middleware
+func (s *service) buildHandler() http.Handler {
+	middleware := []Middleware{s.recoverMiddleware}
+	if s.readinessGate != nil && s.readinessGate.blockAll {
+		middleware = append(middleware, s.readinessGateMiddleware)
+	}
+	if s.forceHTTPS {
+		middleware = append(middleware, s.forceHTTPSMiddleware)
+	}
+	if s.http3 {
+		middleware = append(middleware, altSvcMiddleware(s.http3Port()))
+	}
+	if s.serverHeader != nil {
+		middleware = append(middleware, serverHeaderMiddleware(*s.serverHeader))
+	}
+	if s.securityHeaders != nil {
+		middleware = append(middleware, securityHeadersMiddleware(*s.securityHeaders))
+	}
+	if s.disableContentSniffing {
+		middleware = append(middleware, noSniffMiddleware)
+	}
+	if s.compression {
+		middleware = append(middleware, s.compressionMiddleware)
+	}
+	if s.maxConcurrentRequests > 0 {
+		middleware = append(middleware, s.concurrencyLimitMiddleware())
+	}
+	if s.bodyLogging != nil {
+		middleware = append(middleware, bodyLoggingMiddleware(*s.bodyLogging))
+	}
+	if len(s.requestTransforms) > 0 || len(s.responseTransforms) > 0 {
+		middleware = append(middleware, s.transformMiddleware)
+	}
+	middleware = append(middleware, s.middleware...)
+	return chain(http.HandlerFunc(s.dispatch), middleware)
+}
+
+// SetHandler replaces the handler a request will be dispatched to, letting middleware re-route a request after it
+// has been matched. It reports false if the request is not being dispatched by a service.
+func SetHandler(r *http.Request, handler http.Handler) bool {
+	request, ok := RequestFromContext(r.Context())
+	if !ok {
+		return false
+	}
+	request.SetHandler(handler)
+	return true
+}
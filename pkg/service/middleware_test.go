@@ -0,0 +1,50 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	s := newTestService(WithMiddleware(trace("first"), trace("second")))
+	s.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	})
+	serve(s.mux, http.MethodGet, "/", nil)
+	if got := strings.Join(order, ","); got != "first,second,handler" {
+		t.Errorf("got order %s, want first,second,handler", got)
+	}
+}
+
+func TestSetHandler(t *testing.T) {
+	experiment := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Experiment") == "b" {
+				SetHandler(r, writeBody("b"))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	s := newTestService(WithMiddleware(experiment))
+	s.GET("/", writeBody("a"))
+	if got := serve(s.mux, http.MethodGet, "/", nil).Body.String(); got != "a" {
+		t.Errorf("got %q without re-routing, want a", got)
+	}
+	if got := serve(s.mux, http.MethodGet, "/", map[string]string{"X-Experiment": "b"}).Body.String(); got != "b" {
+		t.Errorf("got %q after re-routing, want b", got)
+	}
+	if SetHandler(httptest.NewRequest(http.MethodGet, "/", nil), writeBody("b")) {
+		t.Error("SetHandler reported true for a request not dispatched by a service")
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// acceptRange is a media range from an Accept header
+type acceptRange struct {
+	typ     string
+	subtype string
+	quality float64
+}
+
+// Negotiate returns the offered media type that best matches the request Accept header, or an empty string when
+// none is acceptable. Media ranges may use type wildcards such as application/* and the full wildcard */*. Offers
+// are listed in order of preference, which breaks ties in quality. Without an Accept header the first offer is used.
+func (r *Request) Negotiate(offers ...string) string {
+	return negotiate(strings.Join(r.httpRequest.Header.Values("Accept"), ","), offers)
+}
+
+func negotiate(accept string, offers []string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return offers[0]
+	}
+	best, bestQuality, bestSpecificity := "", 0.0, -1
+	for _, offer := range offers {
+		quality, specificity := matchOffer(ranges, offer)
+		if quality > bestQuality || (quality == bestQuality && quality > 0 && specificity > bestSpecificity) {
+			best, bestQuality, bestSpecificity = offer, quality, specificity
+		}
+	}
+	return best
+}
+
+// matchOffer returns the quality of the most specific range matching the offer along with its specificity
+func matchOffer(ranges []acceptRange, offer string) (float64, int) {
+	typ, subtype, _ := strings.Cut(strings.ToLower(offer), "/")
+	quality, specificity := 0.0, -1
+	for _, ar := range ranges {
+		var s int
+		switch {
+		case ar.typ == typ && ar.subtype == subtype:
+			s = 2
+		case ar.typ == typ && ar.subtype == "*":
+			s = 1
+		case ar.typ == "*" && ar.subtype == "*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			quality, specificity = ar.quality, s
+		}
+	}
+	return quality, specificity
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{typ: typ, subtype: subtype, quality: quality})
+	}
+	return ranges
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		offers []string
+		want   string
+	}{
+		{"application/*", []string{"text/plain", "application/json"}, "application/json"},
+		{"text/*", []string{"application/json", "text/plain"}, "text/plain"},
+		{"*/*", []string{"application/json", "text/plain"}, "application/json"},
+		{"text/plain;q=0.5, application/json;q=0.9", []string{"text/plain", "application/json"}, "application/json"},
+		{"text/*;q=0.5, text/html;q=0", []string{"text/html"}, ""},
+		{"image/png", []string{"application/json"}, ""},
+		{"", []string{"application/xml", "application/json"}, "application/xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			request := NewRequest(context.Background(), r, httptest.NewRecorder())
+			if got := request.Negotiate(tt.offers...); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// WithOpenAPIEndpoint serves the OpenAPI document of the service, as produced by OpenAPI, at the path, such as
+// /openapi.json
+func WithOpenAPIEndpoint(path string) Option {
+	return func(o *Options) {
+		o.openAPIPath = path
+	}
+}
+
+// WithOpenAPIInfo sets the title and version of the API in its OpenAPI document, which default to API and 1.0.0
+func WithOpenAPIInfo(title, version string) Option {
+	return func(o *Options) {
+		o.openAPITitle = title
+		o.openAPIVersion = version
+	}
+}
+
+type openAPIDocument struct {
+	OpenAPI string                                  `json:"openapi"`
+	Info    openAPIInfo                             `json:"info"`
+	Paths   map[string]map[string]*openAPIOperation `json:"paths"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIOperation struct {
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema map[string]interface{} `json:"schema"`
+}
+
+type openAPIParameter struct {
+	Name     string            `json:"name"`
+	In       string            `json:"in"`
+	Required bool              `json:"required"`
+	Schema   map[string]string `json:"schema"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPI returns a minimal OpenAPI 3 document listing the registered routes with their path parameters and any
+// metadata set when they were registered. Routes registered with Any are left out, as they do not name their
+// methods.
+func (s *service) OpenAPI() ([]byte, error) {
+	document := openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: s.openAPITitle, Version: s.openAPIVersion},
+		Paths:   make(map[string]map[string]*openAPIOperation),
+	}
+	if document.Info.Title == "" {
+		document.Info.Title = "API"
+	}
+	if document.Info.Version == "" {
+		document.Info.Version = "1.0.0"
+	}
+	for _, r := range s.routes.list() {
+		if r.method == anyMethod {
+			continue
+		}
+		path, parameters := openAPIPath(r.segments)
+		operations, ok := document.Paths[path]
+		if !ok {
+			operations = make(map[string]*openAPIOperation)
+			document.Paths[path] = operations
+		}
+		operations[strings.ToLower(r.method)] = openAPIOperationFor(r.metadata, parameters)
+	}
+	return json.Marshal(document)
+}
+
+// openAPIOperationFor describes a route with its metadata. Routes with a response type document it as their 200
+// response.
+func openAPIOperationFor(metadata RouteMetadata, parameters []openAPIParameter) *openAPIOperation {
+	operation := &openAPIOperation{
+		Summary:     metadata.Summary,
+		Description: metadata.Description,
+		Tags:        metadata.Tags,
+		Parameters:  parameters,
+		Responses:   map[string]openAPIResponse{"default": {Description: "Response"}},
+	}
+	if metadata.RequestType != nil {
+		operation.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content: map[string]openAPIMediaType{
+				"application/json": {Schema: jsonSchema(metadata.RequestType, make(map[reflect.Type]bool))},
+			},
+		}
+	}
+	if metadata.ResponseType != nil {
+		operation.Responses = map[string]openAPIResponse{"200": {
+			Description: "OK",
+			Content: map[string]openAPIMediaType{
+				"application/json": {Schema: jsonSchema(metadata.ResponseType, make(map[reflect.Type]bool))},
+			},
+		}}
+	}
+	return operation
+}
+
+// openAPIPath converts route pattern segments to an OpenAPI path template and its path parameters. A remainder
+// wildcard such as {rest...} becomes a single {rest} parameter.
+func openAPIPath(segments []string) (string, []openAPIParameter) {
+	var parameters []openAPIParameter
+	path := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if isWildcard(segment) {
+			name := wildcardName(segment)
+			segment = "{" + name + "}"
+			parameters = append(parameters, openAPIParameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   map[string]string{"type": "string"},
+			})
+		}
+		path = append(path, segment)
+	}
+	return "/" + strings.Join(path, "/"), parameters
+}
+
+func (s *service) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	data, err := s.OpenAPI()
+	if err != nil {
+		s.InternalServerError(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// list returns the registered routes in order of registration
+func (rt *router) list() []*route {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	routes := make([]*route, len(rt.routes))
+	copy(routes, rt.routes)
+	return routes
+}
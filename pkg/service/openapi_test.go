@@ -0,0 +1,60 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	s := newTestService(WithOpenAPIEndpoint("/openapi.json"), WithOpenAPIInfo("Users", "2.0.0"))
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	s.GET("/users/{id}", noop, WithSummary("Get a user"), WithResponseType(user{}))
+	s.POST("/users", noop, WithRequestType(user{}))
+	s.Any("/proxy", noop)
+	w := serve(s.mux, http.MethodGet, "/openapi.json", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", w.Code)
+	}
+	var document openAPIDocument
+	if err := json.Unmarshal(w.Body.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+	if document.OpenAPI != "3.0.3" || document.Info != (openAPIInfo{Title: "Users", Version: "2.0.0"}) {
+		t.Errorf("got version %s and info %+v", document.OpenAPI, document.Info)
+	}
+	if _, ok := document.Paths["/proxy"]; ok {
+		t.Error("got a path for a route registered with Any, want it left out")
+	}
+	get := document.Paths["/users/{id}"]["get"]
+	if get == nil {
+		t.Fatalf("got paths %v, want GET /users/{id}", document.Paths)
+	}
+	if get.Summary != "Get a user" {
+		t.Errorf("got summary %q, want Get a user", get.Summary)
+	}
+	wantParameters := []openAPIParameter{{Name: "id", In: "path", Required: true,
+		Schema: map[string]string{"type": "string"}}}
+	if !reflect.DeepEqual(get.Parameters, wantParameters) {
+		t.Errorf("got parameters %+v, want %+v", get.Parameters, wantParameters)
+	}
+	wantSchema := map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+		"name": map[string]interface{}{"type": "string"},
+		"age":  map[string]interface{}{"type": "integer"},
+	}}
+	if got := get.Responses["200"].Content["application/json"].Schema; !reflect.DeepEqual(got, wantSchema) {
+		t.Errorf("got response schema %v, want %v", got, wantSchema)
+	}
+	post := document.Paths["/users"]["post"]
+	if post == nil || post.RequestBody == nil {
+		t.Fatalf("got paths %v, want POST /users with a request body", document.Paths)
+	}
+	if got := post.RequestBody.Content["application/json"].Schema; !reflect.DeepEqual(got, wantSchema) {
+		t.Errorf("got request schema %v, want %v", got, wantSchema)
+	}
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MethodOverrideHeader is the header used by constrained clients to tunnel other methods through POST
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// overridableMethods are the methods a POST request may be rewritten to
+var overridableMethods = map[string]bool{
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// overrideMethod rewrites the method of a POST request carrying an allowed X-HTTP-Method-Override header
+func overrideMethod(r *http.Request) {
+	if r.Method != http.MethodPost {
+		return
+	}
+	method := strings.ToUpper(strings.TrimSpace(r.Header.Get(MethodOverrideHeader)))
+	if overridableMethods[method] {
+		r.Method = method
+	}
+}
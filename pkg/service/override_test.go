@@ -0,0 +1,35 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMethodOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		method   string
+		override string
+		want     string
+	}{
+		{"post to delete", true, http.MethodPost, "delete", "delete"},
+		{"post to put", true, http.MethodPost, "PUT", "put"},
+		{"disallowed method", true, http.MethodPost, "GET", "post"},
+		{"only from post", true, http.MethodGet, "DELETE", "get"},
+		{"disabled", false, http.MethodPost, "DELETE", "post"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(WithMethodOverride(tt.enabled))
+			s.GET("/items/{id}", writeBody("get"))
+			s.POST("/items/{id}", writeBody("post"))
+			s.PUT("/items/{id}", writeBody("put"))
+			s.DELETE("/items/{id}", writeBody("delete"))
+			w := serve(s.mux, tt.method, "/items/1", map[string]string{MethodOverrideHeader: tt.override})
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("got %q, want the %s handler", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// WithTrustedProxies sets the proxies, as CIDRs or single addresses, whose forwarding headers are honoured when
+// resolving the client address
+func WithTrustedProxies(cidrs []string) Option {
+	return func(o *Options) {
+		o.trustedProxies = append(o.trustedProxies, cidrs...)
+	}
+}
+
+// WithProxyHeaders rewrites the remote address, scheme and host of requests arriving from a trusted proxy with the
+// values it forwarded in the RFC 7239 Forwarded header or the X-Forwarded-For, X-Forwarded-Proto and
+// X-Forwarded-Host headers. Those headers are removed from requests not arriving from a trusted proxy.
+func WithProxyHeaders(proxyHeaders bool) Option {
+	return func(o *Options) {
+		o.proxyHeaders = proxyHeaders
+	}
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func (o *Options) isTrustedProxy(addr netip.Addr) bool {
+	if o == nil || !addr.IsValid() {
+		return false
+	}
+	for _, prefix := range o.trustedPrefixes {
+		if prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client. Forwarding headers are walked from the nearest hop outwards only while
+// each hop is a trusted proxy, so that clients cannot spoof their address by sending the headers themselves.
+func (r *Request) ClientIP() string {
+	peer, err := parseHostAddr(r.httpRequest.RemoteAddr)
+	if err != nil {
+		return r.httpRequest.RemoteAddr
+	}
+	client, _, _ := r.options.resolveForwarded(peer, r.httpRequest.Header)
+	return client.String()
+}
+
+// applyProxyHeaders normalizes the forwarded headers of the request into its remote address, scheme and host
+func (s *service) applyProxyHeaders(request *Request) {
+	r := request.HTTPRequest()
+	peer, err := parseHostAddr(r.RemoteAddr)
+	if err != nil || !s.isTrustedProxy(peer) {
+		for _, name := range []string{"Forwarded", "X-Forwarded-For", "X-Forwarded-Proto", "X-Forwarded-Host"} {
+			r.Header.Del(name)
+		}
+		return
+	}
+	client, proto, host := s.resolveForwarded(peer, r.Header)
+	r.RemoteAddr = client.String()
+	if proto = strings.ToLower(proto); proto == "http" || proto == "https" {
+		r.URL.Scheme = proto
+	}
+	if host != "" {
+		r.Host = host
+		r.URL.Host = host
+	}
+}
+
+// forwardedHop is a hop recorded by a proxy: the address it received the request from, and the scheme and host that
+// were requested of it
+type forwardedHop struct {
+	addr  string
+	proto string
+	host  string
+}
+
+// resolveForwarded returns the address of the client along with the scheme and host it requested. The hops are
+// walked from the nearest outwards only while each is a trusted proxy, and the scheme and host are read from the hop
+// the walk stopped at, or failing that from the nearest proxy that recorded them, so that none of the values can be
+// spoofed by the client.
+func (o *Options) resolveForwarded(peer netip.Addr, header http.Header) (netip.Addr, string, string) {
+	if !o.isTrustedProxy(peer) {
+		return peer, "", ""
+	}
+	hops := forwardedHops(header)
+	recorded := len(hops)
+	for i := len(hops) - 1; i >= 0; i-- {
+		// The hop was recorded by a trusted proxy, so its scheme and host can be relied on even without an address
+		recorded = i
+		addr, err := parseHostAddr(hops[i].addr)
+		if err != nil {
+			break
+		}
+		peer = addr
+		if !o.isTrustedProxy(peer) {
+			break
+		}
+	}
+	var proto, host string
+	for _, hop := range hops[recorded:] {
+		if proto == "" {
+			proto = hop.proto
+		}
+		if host == "" {
+			host = hop.host
+		}
+	}
+	return peer, proto, host
+}
+
+// forwardedHops returns the hops recorded by proxies, from the outermost to the nearest, preferring the RFC 7239
+// Forwarded header over the X-Forwarded headers. The X-Forwarded-Proto and X-Forwarded-Host values are matched to
+// the X-Forwarded-For hops when there are as many of them, and otherwise taken to be set by the nearest proxy.
+func forwardedHops(header http.Header) []forwardedHop {
+	var hops []forwardedHop
+	for _, element := range forwardedElements(header) {
+		hops = append(hops, forwardedHop{addr: element["for"], proto: element["proto"], host: element["host"]})
+	}
+	if len(hops) > 0 {
+		return hops
+	}
+	for _, addr := range headerList(header, "X-Forwarded-For") {
+		hops = append(hops, forwardedHop{addr: addr})
+	}
+	protos, hosts := headerList(header, "X-Forwarded-Proto"), headerList(header, "X-Forwarded-Host")
+	if len(hops) == 0 {
+		if len(protos) == 0 && len(hosts) == 0 {
+			return nil
+		}
+		// The nearest proxy forwarded the scheme or host without the client address
+		hops = append(hops, forwardedHop{})
+	}
+	if len(protos) == len(hops) {
+		for i := range hops {
+			hops[i].proto = protos[i]
+		}
+	} else if len(protos) > 0 {
+		hops[len(hops)-1].proto = protos[len(protos)-1]
+	}
+	if len(hosts) == len(hops) {
+		for i := range hops {
+			hops[i].host = hosts[i]
+		}
+	} else if len(hosts) > 0 {
+		hops[len(hops)-1].host = hosts[len(hosts)-1]
+	}
+	return hops
+}
+
+// headerList returns the values of a comma separated list header, across all of its lines
+func headerList(header http.Header, name string) []string {
+	var list []string
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// forwardedElements parses the RFC 7239 Forwarded header into its elements, one per proxy hop
+func forwardedElements(header http.Header) []map[string]string {
+	var elements []map[string]string
+	for _, value := range header.Values("Forwarded") {
+		for _, element := range strings.Split(value, ",") {
+			pairs := make(map[string]string)
+			for _, pair := range strings.Split(element, ";") {
+				key, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				pairs[strings.ToLower(key)] = strings.Trim(v, `"`)
+			}
+			elements = append(elements, pairs)
+		}
+	}
+	return elements
+}
+
+// parseHostAddr parses an address with an optional port, such as a RemoteAddr or a forwarded hop
+func parseHostAddr(value string) (netip.Addr, error) {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(value, "[]"))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap(), nil
+}
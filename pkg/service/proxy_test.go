@@ -0,0 +1,99 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	s := newTestService(WithTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"}))
+	s.GET("/ip", func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		w.Write([]byte(request.ClientIP()))
+	})
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		xff       string
+		want      string
+	}{
+		{"untrusted peer", "1.2.3.4:5000", "", "9.9.9.9", "1.2.3.4"},
+		{"trusted chain", "10.0.0.1:5000", "", "9.9.9.9, 10.1.1.1", "9.9.9.9"},
+		{"spoofed leftmost hop", "10.0.0.1:5000", "", "6.6.6.6, 9.9.9.9", "9.9.9.9"},
+		{"forwarded header", "192.168.1.1:5000", `for="[2001:db8::1]:4711", for=10.2.2.2`, "", "2001:db8::1"},
+		{"no forwarding headers", "10.0.0.1:5000", "", "", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			r.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				r.Header.Set("Forwarded", tt.forwarded)
+			}
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, r)
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyHeaders(t *testing.T) {
+	s := newTestService(WithProxyHeaders(true), WithTrustedProxies([]string{"10.0.0.0/8"}))
+	s.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr + " " + r.URL.Scheme + " " + r.Host))
+	})
+	tests := []struct {
+		name   string
+		remote string
+		header map[string]string
+		want   string
+	}{
+		{"untrusted peer", "1.2.3.4:5000",
+			map[string]string{"X-Forwarded-For": "9.9.9.9", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "a.example"},
+			"1.2.3.4:5000  example.com"},
+		{"single proxy", "10.0.0.1:5000",
+			map[string]string{"X-Forwarded-For": "9.9.9.9", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "a.example"},
+			"9.9.9.9 https a.example"},
+		{"spoofed leftmost hop", "10.0.0.1:5000",
+			map[string]string{"X-Forwarded-For": "6.6.6.6, 9.9.9.9", "X-Forwarded-Proto": "http, https",
+				"X-Forwarded-Host": "evil.example, a.example"},
+			"9.9.9.9 https a.example"},
+		{"values set by the nearest proxy", "10.0.0.1:5000",
+			map[string]string{"X-Forwarded-For": "6.6.6.6, 9.9.9.9", "X-Forwarded-Proto": "https"},
+			"9.9.9.9 https example.com"},
+		{"trusted chain", "10.0.0.1:5000",
+			map[string]string{"X-Forwarded-For": "9.9.9.9, 10.1.1.1", "X-Forwarded-Proto": "https, http",
+				"X-Forwarded-Host": "a.example, internal.example"},
+			"9.9.9.9 https a.example"},
+		{"scheme without address", "10.0.0.1:5000",
+			map[string]string{"X-Forwarded-Proto": "https"},
+			"10.0.0.1 https example.com"},
+		{"forwarded header", "10.0.0.1:5000",
+			map[string]string{"Forwarded": `for=6.6.6.6;proto=http;host=evil.example, for=9.9.9.9;proto=https;host=a.example`},
+			"9.9.9.9 https a.example"},
+		{"forwarded by a trusted proxy without values", "10.0.0.1:5000",
+			map[string]string{"Forwarded": `for=9.9.9.9, for=10.1.1.1;proto=https;host=a.example`},
+			"9.9.9.9 https a.example"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for name, value := range tt.header {
+				r.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, r)
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
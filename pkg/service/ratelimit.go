@@ -0,0 +1,93 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WithRouteRateLimit limits each client, identified by its IP, to limit requests per period on the route, allowing
+// bursts of up to limit requests. Requests over the limit are rejected with 429 and a Retry-After header. Routes
+// registered with the same option value, such as the methods of a Handle call, share the budget. A limit or period
+// that is not positive is a programming error and panics, as net/http does for invalid patterns.
+func WithRouteRateLimit(limit int, per time.Duration) RouteOption {
+	if limit <= 0 || per <= 0 {
+		panic(fmt.Sprintf("invalid rate limit of %d requests per %v", limit, per))
+	}
+	limiter := newRateLimiter(limit, per)
+	return WithRouteMiddleware(limiter.middleware)
+}
+
+// rateLimiter is a token bucket per client
+type rateLimiter struct {
+	mu        sync.Mutex
+	burst     float64
+	rate      float64 // tokens per second
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(limit int, per time.Duration) *rateLimiter {
+	return &rateLimiter{
+		burst:   float64(limit),
+		rate:    float64(limit) / per.Seconds(),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token from the bucket of the client, returning the wait until one is available when it is empty
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := clk.Now()
+	// Buckets refilled to the burst are no different from new ones, and are swept out at most once a minute
+	if now.Sub(l.lastSweep) > time.Minute {
+		for key, bucket := range l.buckets {
+			if bucket.refill(now, l.rate, l.burst) >= l.burst {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+	if bucket.refill(now, l.rate, l.burst) < 1 {
+		return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// refill adds the tokens accrued since the bucket was last refilled, returning the tokens available
+func (b *tokenBucket) refill(now time.Time, rate, burst float64) float64 {
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	return b.tokens
+}
+
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, ok := RequestFromContext(r.Context())
+		if !ok || request.service == nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if ok, wait := l.allow(request.ClientIP()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			request.service.TooManyRequests(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRouteRateLimit(t *testing.T) {
+	fake := useFakeClock(t)
+	s := newTestService(WithTrustedProxies([]string{"192.0.2.1"}))
+	s.GET("/limited", writeBody("ok"), WithRouteRateLimit(2, time.Second))
+	s.GET("/open", writeBody("ok"))
+	get := func(path, client string) *http.Response {
+		return serve(s.mux, http.MethodGet, path, map[string]string{"X-Forwarded-For": client}).Result()
+	}
+	for i := 0; i < 2; i++ {
+		if resp := get("/limited", "9.9.9.9"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: got status %d, want 200", i+1, resp.StatusCode)
+		}
+	}
+	resp := get("/limited", "9.9.9.9")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "1" {
+		t.Errorf("over the limit: got status %d with Retry-After %q, want 429 with 1", resp.StatusCode,
+			resp.Header.Get("Retry-After"))
+	}
+	if resp := get("/limited", "8.8.8.8"); resp.StatusCode != http.StatusOK {
+		t.Errorf("other client: got status %d, want 200", resp.StatusCode)
+	}
+	if resp := get("/open", "9.9.9.9"); resp.StatusCode != http.StatusOK {
+		t.Errorf("other route: got status %d, want 200", resp.StatusCode)
+	}
+	fake.Advance(500 * time.Millisecond)
+	if resp := get("/limited", "9.9.9.9"); resp.StatusCode != http.StatusOK {
+		t.Errorf("after the window: got status %d, want 200", resp.StatusCode)
+	}
+	if resp := get("/limited", "9.9.9.9"); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("after the refill is spent: got status %d, want 429", resp.StatusCode)
+	}
+}
+
+func TestRouteRateLimitInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		per   time.Duration
+	}{
+		{"zero limit", 0, time.Second},
+		{"negative limit", -1, time.Second},
+		{"zero period", 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("got no panic for %d requests per %v", tt.limit, tt.per)
+				}
+			}()
+			WithRouteRateLimit(tt.limit, tt.per)
+		})
+	}
+}
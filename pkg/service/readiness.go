@@ -0,0 +1,40 @@
+package service
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// readinessGate holds traffic back until the service has been released
+type readinessGate struct {
+	released atomic.Bool
+	blockAll bool
+}
+
+// WithReadinessGate returns an option failing the /readyz readiness probe until the returned release function is
+// called, for example once caches are warm. When blockAll is set, every routed request is answered with 503 until
+// then as well. The release function may be called more than once.
+func WithReadinessGate(blockAll bool) (Option, func()) {
+	gate := &readinessGate{blockAll: blockAll}
+	return func(o *Options) {
+		o.readinessGate = gate
+	}, func() {
+		gate.released.Store(true)
+	}
+}
+
+// ready reports whether the readiness gate, if any, has been released
+func (o *Options) ready() bool {
+	return o.readinessGate == nil || o.readinessGate.released.Load()
+}
+
+// readinessGateMiddleware answers requests with 503 until the readiness gate is released
+func (s *service) readinessGateMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.ready() {
+			s.ServiceUnavailable(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReadinessGate(t *testing.T) {
+	tests := []struct {
+		name      string
+		blockAll  bool
+		routeCode int
+	}{
+		{"probe only", false, http.StatusOK},
+		{"all requests", true, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate, release := WithReadinessGate(tt.blockAll)
+			s := newTestService(gate)
+			s.GET("/route", writeBody("ok"))
+			if code := serve(s.mux, http.MethodGet, "/readyz", nil).Code; code != http.StatusServiceUnavailable {
+				t.Errorf("before release: got readyz %d, want 503", code)
+			}
+			if code := serve(s.mux, http.MethodGet, "/livez", nil).Code; code != http.StatusOK {
+				t.Errorf("before release: got livez %d, want 200", code)
+			}
+			if code := serve(s.mux, http.MethodGet, "/route", nil).Code; code != tt.routeCode {
+				t.Errorf("before release: got route %d, want %d", code, tt.routeCode)
+			}
+			release()
+			release()
+			for _, path := range []string{"/readyz", "/route"} {
+				if code := serve(s.mux, http.MethodGet, path, nil).Code; code != http.StatusOK {
+					t.Errorf("after release: got %s %d, want 200", path, code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// RecoverHandler responds to a request whose handler panicked with the recovered value
+type RecoverHandler func(r *Request, recovered interface{})
+
+// WithRecoverHandler replaces the 500 response written when a handler panics. The panic and its stack are logged
+// before the handler is called.
+func WithRecoverHandler(recoverHandler RecoverHandler) Option {
+	return func(o *Options) {
+		o.recoverHandler = recoverHandler
+	}
+}
+
+// WithPanicStackInResponse includes the panic value and stack trace in the body of the 500 response to a panicking
+// handler. It is meant for local development only: the stack reveals source paths and internal state to whoever
+// sent the request, so it must never be enabled in production. It has no effect when a recover handler is set.
+func WithPanicStackInResponse(panicStackInResponse bool) Option {
+	return func(o *Options) {
+		o.panicStackInResponse = panicStackInResponse
+	}
+}
+
+// recoverMiddleware recovers from panics in the middleware chain and handler, logging them and responding with 500
+// unless the handler had already started its response. A response still held by WithResponseBuffering has not been
+// started, and is discarded for the 500. http.ErrAbortHandler is passed on to the server so that the
+// response is aborted.
+func (s *service) recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := NewTrackingWriter(w)
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered)
+			}
+			request, ok := RequestFromContext(r.Context())
+			if !ok {
+				panic(recovered)
+			}
+			stack := debug.Stack()
+			request.Logger().ErrorContext(request.Context(), "handler panicked", "panic", recovered,
+				"stack", string(stack))
+			if discarder, ok := w.(interface{ discard() bool }); tw.Written() && (!ok || !discarder.discard()) {
+				return
+			}
+			if s.recoverHandler != nil {
+				// The handler may have been given another writer, which the panic has left unusable
+				request.writer = w
+				s.recoverHandler(request, recovered)
+				return
+			}
+			if s.panicStackInResponse {
+				s.ErrorResponse(w, r, http.StatusInternalServerError,
+					fmt.Sprintf("Internal Server Error\n\npanic: %v\n\n%s", recovered, stack))
+				return
+			}
+			s.InternalServerError(w, r)
+		}()
+		next.ServeHTTP(tw, r)
+	})
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecover(t *testing.T) {
+	customHandler := WithRecoverHandler(func(r *Request, recovered interface{}) {
+		r.Writer().WriteHeader(http.StatusServiceUnavailable)
+		_, _ = fmt.Fprint(r.Writer(), recovered)
+	})
+	tests := []struct {
+		name string
+		opts []Option
+		code int
+		body string
+	}{
+		{"default", nil, http.StatusInternalServerError, ""},
+		{"custom handler", []Option{customHandler}, http.StatusServiceUnavailable, "boom"},
+		{"handler timeout", []Option{WithHandlerTimeout(time.Second)}, http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(tt.opts...)
+			s.GET("/panic", func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			})
+			w := serve(s.mux, http.MethodGet, "/panic", nil)
+			if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("got status %d with body %q, want %d with %q", w.Code, w.Body.String(), tt.code, tt.body)
+			}
+		})
+	}
+}
+
+func TestPanicStackInResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+	}{
+		{"disabled", false},
+		{"enabled", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(WithPanicStackInResponse(tt.enabled))
+			s.GET("/panic", func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			})
+			w := serve(s.mux, http.MethodGet, "/panic", nil)
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("got status %d, want 500", w.Code)
+			}
+			body := w.Body.String()
+			if got := strings.Contains(body, "panic: boom") && strings.Contains(body, "goroutine"); got != tt.enabled {
+				t.Errorf("got the stack in the body %v, want %v: %q", got, tt.enabled, body)
+			}
+		})
+	}
+}
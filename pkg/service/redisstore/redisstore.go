@@ -0,0 +1,132 @@
+// Package redisstore provides a Redis backed sessions.Store for use with service.WithSessionStore. It lives in its
+// own package so that services not using Redis do not link the client.
+package redisstore
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/gob"
+	"errors"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/redis/go-redis/v9"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultMaxAge is the default session lifetime in seconds
+	defaultMaxAge = 86400 * 30
+)
+
+var sessionIDEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// RedisStore is a sessions.Store keeping session values in Redis, with only the session ID stored in the cookie.
+// Sessions expire in Redis after Options.MaxAge seconds.
+type RedisStore struct {
+	Codecs    []securecookie.Codec
+	Options   *sessions.Options
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+// NewRedisStore returns a RedisStore storing sessions under keys with the given prefix. When codecs are given the
+// session ID cookie is signed and optionally encrypted with them.
+func NewRedisStore(client redis.UniversalClient, keyPrefix string, codecs ...securecookie.Codec) *RedisStore {
+	return &RedisStore{
+		Codecs: codecs,
+		Options: &sessions.Options{
+			Path:   "/",
+			MaxAge: defaultMaxAge,
+		},
+		client:    client,
+		keyPrefix: keyPrefix,
+	}
+}
+
+// Get returns the named session after adding it to the request registry
+func (s *RedisStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns the named session, loading its values when the request carries a live session ID
+func (s *RedisStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var id string
+	if err := s.decodeID(name, c.Value, &id); err != nil {
+		return session, err
+	}
+	data, err := s.client.Get(r.Context(), s.key(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values); err != nil {
+		return session, err
+	}
+	session.ID = id
+	session.IsNew = false
+	return session, nil
+}
+
+// Save stores the session values and writes the session ID cookie. A session with Options.MaxAge < 0 is deleted.
+func (s *RedisStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.client.Del(r.Context(), s.key(session.ID)).Err(); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+	if session.ID == "" {
+		session.ID = sessionIDEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+	}
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(session.Values); err != nil {
+		return err
+	}
+	maxAge := session.Options.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultMaxAge
+	}
+	ttl := time.Duration(maxAge) * time.Second
+	if err := s.client.Set(r.Context(), s.key(session.ID), data.Bytes(), ttl).Err(); err != nil {
+		return err
+	}
+	encoded, err := s.encodeID(session.Name(), session.ID)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+func (s *RedisStore) key(id string) string {
+	return s.keyPrefix + id
+}
+
+func (s *RedisStore) encodeID(name, id string) (string, error) {
+	if len(s.Codecs) == 0 {
+		return id, nil
+	}
+	return securecookie.EncodeMulti(name, id, s.Codecs...)
+}
+
+func (s *RedisStore) decodeID(name, value string, id *string) error {
+	if len(s.Codecs) == 0 {
+		*id = value
+		return nil
+	}
+	return securecookie.DecodeMulti(name, value, id, s.Codecs...)
+}
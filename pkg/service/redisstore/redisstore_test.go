@@ -0,0 +1,75 @@
+package redisstore
+
+import (
+	"github.com/gorilla/securecookie"
+	"github.com/redis/go-redis/v9"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// newTestStore returns a store backed by the Redis server at REDIS_ADDR, skipping the test when it is not set
+func newTestStore(t *testing.T) *RedisStore {
+	t.Helper()
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR not set")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return NewRedisStore(client, "test-session:", securecookie.CodecsFromPairs([]byte("0123456789abcdef"))...)
+}
+
+func TestRedisStore(t *testing.T) {
+	store := newTestStore(t)
+
+	session, err := store.New(httptest.NewRequest(http.MethodGet, "/", nil), "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values["user"] = "alice"
+	w := httptest.NewRecorder()
+	if err := store.Save(httptest.NewRequest(http.MethodGet, "/", nil), w, session); err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	load := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cookies[0])
+		return r
+	}
+
+	loaded, err := store.New(load(), "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.IsNew || loaded.Values["user"] != "alice" {
+		t.Errorf("got new %v with user %v, want the saved session", loaded.IsNew, loaded.Values["user"])
+	}
+
+	loaded.Options.MaxAge = -1
+	if err := store.Save(load(), httptest.NewRecorder(), loaded); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := store.New(load(), "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !deleted.IsNew {
+		t.Error("got the deleted session back, want a new one")
+	}
+}
+
+func TestRedisStoreWithoutCookie(t *testing.T) {
+	store := NewRedisStore(redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"}), "test-session:")
+	session, err := store.New(httptest.NewRequest(http.MethodGet, "/", nil), "session")
+	if err != nil || !session.IsNew {
+		t.Errorf("got new %v with error %v, want a new session", session.IsNew, err)
+	}
+}
@@ -1,9 +1,16 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"github.com/google/uuid"
+	"io"
+	"log/slog"
 	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -17,6 +24,32 @@ type Request struct {
 	httpRequest *http.Request
 	writer      http.ResponseWriter
 	ctx         context.Context
+	// clientCtx is the context the request arrived with, which is done once the client has gone
+	clientCtx context.Context
+	// shutdown is closed when the service begins shutting down
+	shutdown <-chan struct{}
+	body     []byte
+	bodyRead bool
+	route    *route
+	params   map[string]string
+	handler  http.Handler
+	options  *Options
+	// service is the service dispatching the request, whose response helpers route middleware answers with
+	service *service
+	start   time.Time
+	// writeDeadline is shared with copies of the request so that stream helpers given one can extend it
+	writeDeadline *writeDeadlineContext
+	values        *requestValues
+	bytesIn       *countingBody
+	tracker       *TrackingWriter
+}
+
+type requestKey struct{}
+
+// RequestFromContext returns the Request being served with the context
+func RequestFromContext(ctx context.Context) (*Request, bool) {
+	r, ok := ctx.Value(requestKey{}).(*Request)
+	return r, ok
 }
 
 func (r *Request) ID() uuid.UUID {
@@ -27,12 +60,26 @@ func (r *Request) Context() context.Context {
 	return r.ctx
 }
 
+// ShuttingDown returns a channel that is closed when the service begins shutting down, so that long-lived handlers
+// can finish and let the server drain. The request contexts of streaming routes are canceled when it is closed,
+// those of other requests only once Stop has finished draining.
+func (r *Request) ShuttingDown() <-chan struct{} {
+	return r.shutdown
+}
+
 func (r *Request) HTTPRequest() *http.Request {
 	return r.httpRequest
 }
 
 func NewRequest(ctx context.Context, httpRequest *http.Request, writer http.ResponseWriter) *Request {
-	return &Request{id: uuid.New(), sessionName: SessionName, ctx: ctx, httpRequest: httpRequest, writer: writer}
+	return &Request{id: uuid.New(), sessionName: SessionName, ctx: ctx, clientCtx: ctx, httpRequest: httpRequest,
+		writer: writer, start: time.Now(), values: &requestValues{}}
+}
+
+// WithID replaces the ID of the request
+func (r *Request) WithID(id uuid.UUID) *Request {
+	r.id = id
+	return r
 }
 
 func (r *Request) WithSessionName(sessionName string) *Request {
@@ -51,3 +98,137 @@ func (r *Request) Writer() http.ResponseWriter {
 func (r *Request) ResponseBuilder() ResponseBuilder {
 	return &responseBuilder{request: r}
 }
+
+// Body reads and caches the request body so that it can be read more than once. The body is left in place for
+// downstream readers of the http.Request, and is subject to the limit set with WithMaxRequestBody.
+func (r *Request) Body() ([]byte, error) {
+	if r.bodyRead {
+		return r.body, nil
+	}
+	if r.httpRequest.Body == nil || r.httpRequest.Body == http.NoBody {
+		r.bodyRead = true
+		return r.body, nil
+	}
+	body, err := io.ReadAll(r.httpRequest.Body)
+	_ = r.httpRequest.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.setBody(body)
+	return body, nil
+}
+
+// setBody caches the body and replays it to downstream readers of the http.Request
+func (r *Request) setBody(body []byte) {
+	r.body = body
+	r.bodyRead = true
+	replay := io.NopCloser(bytes.NewReader(body))
+	if shared, ok := r.httpRequest.Body.(*replayableBody); ok {
+		shared.ReadCloser = replay
+	} else {
+		r.httpRequest.Body = replay
+	}
+}
+
+// replayableBody is installed as the request body before the request is handed to middleware, so that a body cached
+// by Body is replayed to every copy of the http.Request made with WithContext, not only the one the Request holds
+type replayableBody struct {
+	io.ReadCloser
+}
+
+// NegotiatedProtocol returns the protocol negotiated through TLS ALPN, or an empty string for plaintext requests
+func (r *Request) NegotiatedProtocol() string {
+	if r.httpRequest.TLS == nil {
+		return ""
+	}
+	return r.httpRequest.TLS.NegotiatedProtocol
+}
+
+// attach stores the request in its context so that it can be retrieved with RequestFromContext
+func (r *Request) attach() {
+	r.ctx = context.WithValue(r.ctx, requestKey{}, r)
+	r.httpRequest = r.httpRequest.WithContext(r.ctx)
+}
+
+// setContext replaces the context of the request
+func (r *Request) setContext(ctx context.Context) {
+	r.ctx = ctx
+	r.httpRequest = r.httpRequest.WithContext(ctx)
+}
+
+// Logger returns a logger annotated with the request ID
+func (r *Request) Logger() *slog.Logger {
+	return slog.Default().With(slog.String("request_id", r.id.String()))
+}
+
+// PathValue returns the value of the named wildcard in the matched route pattern
+func (r *Request) PathValue(name string) string {
+	return r.params[name]
+}
+
+// SetHandler replaces the handler the request will be dispatched to
+func (r *Request) SetHandler(handler http.Handler) {
+	r.handler = handler
+}
+
+// RoutePattern returns the pattern of the matched route, such as /users/{id}, or an empty string for unrouted
+// requests. Unlike the request path it has a bounded set of values, suiting log fields and metric labels.
+func (r *Request) RoutePattern() string {
+	if r.route == nil {
+		return ""
+	}
+	return r.route.pattern
+}
+
+// AddWarning appends an RFC 7234 Warning header, such as 110 - "Response is Stale", to the response
+func (r *Request) AddWarning(code int, text string) {
+	text = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text)
+	r.writer.Header().Add("Warning", fmt.Sprintf(`%03d - "%s"`, code, text))
+}
+
+// SetTrailer sets a trailer sent after the response body. It may be called after the body has been written, and the
+// trailer need not have been declared in the Trailer header beforehand.
+func (r *Request) SetTrailer(name, value string) {
+	r.writer.Header().Set(http.TrailerPrefix+name, value)
+}
+
+// EarlyHints sends a 103 Early Hints informational response with a Link header per link, such as
+// </app.css>; rel=preload; as=style, so that the client can start fetching them while the final response is
+// prepared. The links are sent with the final response as well. HTTP/1.0 clients are sent nothing.
+func (r *Request) EarlyHints(links []string) {
+	if len(links) == 0 || !r.httpRequest.ProtoAtLeast(1, 1) {
+		return
+	}
+	for _, link := range links {
+		r.writer.Header().Add("Link", link)
+	}
+	r.writer.WriteHeader(http.StatusEarlyHints)
+}
+
+// BytesIn returns the number of request body bytes read so far, as received before any decompression
+func (r *Request) BytesIn() int64 {
+	if r.bytesIn == nil {
+		return 0
+	}
+	return r.bytesIn.n.Load()
+}
+
+// BytesOut returns the number of response body bytes written so far, as sent after any compression
+func (r *Request) BytesOut() int64 {
+	if r.tracker == nil {
+		return 0
+	}
+	return r.tracker.written
+}
+
+// countingBody counts the bytes read from a request body
+type countingBody struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return n, err
+}
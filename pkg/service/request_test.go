@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestNegotiatedProtocol(t *testing.T) {
+	var got string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = NewRequest(r.Context(), r, w).NegotiatedProtocol()
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got != "h2" {
+		t.Errorf("got protocol %q, want h2", got)
+	}
+
+	plain := NewRequest(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	if p := plain.NegotiatedProtocol(); p != "" {
+		t.Errorf("got protocol %q for a plaintext request, want none", p)
+	}
+}
+
+func TestRequestFromContext(t *testing.T) {
+	s := newTestService()
+	s.GET("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		request, ok := RequestFromContext(r.Context())
+		if !ok {
+			t.Error("request missing from the handler context")
+			return
+		}
+		w.Write([]byte(request.PathValue("id")))
+	})
+	if got := serve(s.mux, http.MethodGet, "/users/7", nil).Body.String(); got != "7" {
+		t.Errorf("got %q, want 7", got)
+	}
+	if _, ok := RequestFromContext(context.Background()); ok {
+		t.Error("got a request from a bare context")
+	}
+}
+
+func TestAddWarning(t *testing.T) {
+	w := httptest.NewRecorder()
+	request := NewRequest(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil), w)
+	request.AddWarning(110, "Response is Stale")
+	request.AddWarning(199, `say "hi"`)
+	want := []string{`110 - "Response is Stale"`, `199 - "say \"hi\""`}
+	got := w.Header().Values("Warning")
+	if len(got) != len(want) {
+		t.Fatalf("got warnings %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got warning %q, want %q", got[i], want[i])
+		}
+	}
+}
+
+func TestBody(t *testing.T) {
+	readBody := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request, _ := RequestFromContext(r.Context())
+			if _, err := request.Body(); err != nil {
+				w.WriteHeader(StatusFromError(err))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	s := newTestService(WithMiddleware(readBody), WithMaxRequestBody(16))
+	s.POST("/body", func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		first, _ := request.Body()
+		second, _ := request.Body()
+		raw, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(string(first) + "|" + string(second) + "|" + string(raw)))
+	})
+	tests := []struct {
+		name string
+		body string
+		code int
+		want string
+	}{
+		{"read twice", "payload", http.StatusOK, "payload|payload|payload"},
+		{"empty", "", http.StatusOK, "||"},
+		{"over the limit", strings.Repeat("a", 17), http.StatusRequestEntityTooLarge, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post(s.mux, "/body", tt.body, nil)
+			if w.Code != tt.code {
+				t.Fatalf("got status %d, want %d", w.Code, tt.code)
+			}
+			if tt.code == http.StatusOK && w.Body.String() != tt.want {
+				t.Errorf("got %q, want %q", w.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEarlyHints(t *testing.T) {
+	s := newTestService()
+	s.GET("/page", func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		request.EarlyHints([]string{"</app.css>; rel=preload; as=style"})
+		_, _ = w.Write([]byte("page"))
+	})
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	var informational []int
+	var hinted []string
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			informational = append(informational, code)
+			hinted = append(hinted, header.Values("Link")...)
+			return nil
+		},
+	}
+	r, err := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet,
+		srv.URL+"/page", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(informational) != 1 || informational[0] != http.StatusEarlyHints {
+		t.Fatalf("got informational responses %v, want 103", informational)
+	}
+	if len(hinted) != 1 || hinted[0] != "</app.css>; rel=preload; as=style" {
+		t.Errorf("got hinted links %v, want the stylesheet", hinted)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Link") != "</app.css>; rel=preload; as=style" {
+		t.Errorf("got status %d with Link %q, want 200 with the stylesheet", resp.StatusCode, resp.Header.Get("Link"))
+	}
+}
+
+func TestRoutePattern(t *testing.T) {
+	var pattern string
+	recordPattern := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+			request, _ := RequestFromContext(r.Context())
+			pattern = request.RoutePattern()
+		})
+	}
+	s := newTestService(WithMiddleware(recordPattern))
+	s.GET("/users/{id}", writeBody("user"))
+	s.GET("/files/{path...}", writeBody("file"))
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/users/7", "/users/{id}"},
+		{"/files/a/b", "/files/{path...}"},
+		{"/unrouted", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			pattern = "unset"
+			serve(s.mux, http.MethodGet, tt.path, nil)
+			if pattern != tt.want {
+				t.Errorf("got %q, want %q", pattern, tt.want)
+			}
+		})
+	}
+}
+
+func TestBytesInOut(t *testing.T) {
+	var in, out int64
+	record := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+			request, _ := RequestFromContext(r.Context())
+			in, out = request.BytesIn(), request.BytesOut()
+		})
+	}
+	s := newTestService(WithMiddleware(record))
+	s.POST("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+		_, _ = w.Write([]byte("!"))
+	})
+	w := post(s.mux, "/echo", "0123456789", nil)
+	if w.Body.String() != "0123456789!" {
+		t.Fatalf("got body %q", w.Body.String())
+	}
+	if in != 10 || out != 11 {
+		t.Errorf("got %d bytes in and %d bytes out, want 10 and 11", in, out)
+	}
+	// Compressed bodies are counted as received
+	s = newTestService(WithMiddleware(record), WithDecompressRequests(true))
+	s.POST("/echo", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, r.Body)
+	})
+	body, err := io.ReadAll(gzipped(t, strings.Repeat("a", 1000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = post(s.mux, "/echo", string(body), map[string]string{"Content-Encoding": "gzip"})
+	if w.Body.Len() != 1000 {
+		t.Fatalf("got %d bytes of body, want 1000", w.Body.Len())
+	}
+	if in != int64(len(body)) || out != 1000 {
+		t.Errorf("got %d bytes in and %d bytes out, want %d and 1000", in, out, len(body))
+	}
+}
@@ -4,10 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"github.com/bchisham/collections-go/contracts"
-	"github.com/bchisham/collections-go/stream"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"syscall"
 )
 
 // ResponseDataFunc is a function that returns the response data. It is used to defer the execution of the response data.
@@ -27,7 +28,8 @@ func StringData(data string) ResponseDataFunc {
 	}
 }
 
-// JSONData returns a ResponseDataFunc that returns the provided data as JSON
+// JSONData returns a ResponseDataFunc that returns the provided data as JSON. Request.JSONData uses the JSON encoder
+// set with WithJSONEncoder instead.
 func JSONData(data interface{}) ResponseDataFunc {
 	return func() ([]byte, error) {
 		return json.Marshal(data)
@@ -36,37 +38,112 @@ func JSONData(data interface{}) ResponseDataFunc {
 
 // BinaryStreamData returns a ResponseDataFunc that returns the provided data
 func BinaryStreamData(ctx context.Context, request Request, ch chan []byte) ResponseDataFunc {
-	s := stream.FromChanWithContext(ch, ctx)
+	return BinaryStreamDataWithCancel(ctx, request, ch, nil)
+}
+
+// BinaryStreamDataWithCancel returns a ResponseDataFunc that streams the provided data, calling cancel with the write
+// error once the client can no longer be written to so that the producer sending on the channel can stop. Passing
+// the cancel function of the context the producer watches ties the producer to the client. The channel belongs to
+// the producer, which closes it to end the stream; streaming also ends once the context is done or the service begins
+// shutting down, when cancel is called with ErrStreamCanceled.
+func BinaryStreamDataWithCancel(ctx context.Context, request Request, ch chan []byte, cancel context.CancelCauseFunc) ResponseDataFunc {
 	w := request.Writer()
-	defer func() {
-		err := s.Close()
-		if err != nil {
-			slog.ErrorContext(ctx, "error closing stream", err)
-		}
-	}()
 	return func() ([]byte, error) {
-		var endOfStream []byte
-		err := s.Each(func(v []byte) error {
-			_, err := w.Write(v)
-			if err != nil {
-				slog.ErrorContext(ctx, "error writing to stream", err)
-				return err
+		if request.contentSniffingDisabled() && w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				slog.DebugContext(ctx, "context done")
+				return nil, nil
+			case <-request.shutdown:
+				slog.DebugContext(ctx, "service shutting down, ending stream")
+				if cancel != nil {
+					cancel(ErrStreamCanceled)
+				}
+				return nil, nil
+			case v, ok := <-ch:
+				if !ok {
+					return nil, nil
+				}
+				request.extendWriteDeadline(w)
+				if _, err := w.Write(v); err != nil {
+					logWriteError(ctx, "error writing to stream", err)
+					if cancel != nil {
+						cancel(err)
+					}
+					return nil, err
+				}
 			}
-			return nil
-		})
-		if err != nil && errors.Is(err, contracts.ErrContextDone) {
-			slog.DebugContext(ctx, "context done")
-			return endOfStream, nil
+		}
+	}
+}
 
+// StreamJSONArray returns a ResponseDataFunc that streams the values received on the channel to the client as a JSON
+// array, closing the array once the channel is closed. If the context is cancelled or the service begins shutting
+// down first, streaming stops, the array is closed so that the output is valid but truncated JSON, and
+// ErrStreamCanceled is returned.
+func StreamJSONArray[T any](ctx context.Context, request *Request, ch <-chan T) ResponseDataFunc {
+	return func() ([]byte, error) {
+		w := request.Writer()
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		if _, err := w.Write([]byte("[")); err != nil {
+			return nil, err
+		}
+		flusher, _ := w.(http.Flusher)
+		for first := true; ; first = false {
+			select {
+			case <-ctx.Done():
+				_, _ = w.Write([]byte("]"))
+				return nil, fmt.Errorf("%w: %v", ErrStreamCanceled, context.Cause(ctx))
+			case <-request.shutdown:
+				_, _ = w.Write([]byte("]"))
+				return nil, fmt.Errorf("%w: service shutting down", ErrStreamCanceled)
+			case v, ok := <-ch:
+				if !ok {
+					_, err := w.Write([]byte("]"))
+					return nil, err
+				}
+				data, err := request.options.encodeJSON(v)
+				if err != nil {
+					_, _ = w.Write([]byte("]"))
+					return nil, err
+				}
+				request.extendWriteDeadline(w)
+				if !first {
+					data = append([]byte(","), data...)
+				}
+				if _, err := w.Write(data); err != nil {
+					logWriteError(ctx, "error writing to stream", err)
+					return nil, err
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
 		}
-		return endOfStream, err
 	}
 }
 
 type ResponseBuilder interface {
+	WithStatus(status int) ResponseBuilder
 	WithBody(body []byte) ResponseBuilder
 	WithHeader(key, value string) ResponseBuilder
 	WithBodyFunc(bodyFunc ResponseDataFunc) ResponseBuilder
+	// WithText sets the body to the text with a text/plain Content-Type
+	WithText(text string) ResponseBuilder
+	// WithHTML sets the body to the HTML with a text/html Content-Type
+	WithHTML(html string) ResponseBuilder
+	// WithJSON sets the body to the value encoded as JSON with an application/json Content-Type
+	WithJSON(v interface{}) ResponseBuilder
+	// WithTrailer declares a trailer whose value is computed once the body has been sent, such as a checksum of a
+	// streamed body
+	WithTrailer(name string, value func() string) ResponseBuilder
+	// Build returns the Response to be sent
+	Build() *Response
 }
 
 type Response struct {
@@ -75,7 +152,15 @@ type Response struct {
 
 type responseBuilder struct {
 	request  *Request
+	status   int
 	bodyFunc ResponseDataFunc
+	trailers []trailer
+}
+
+// trailer is a trailer declared on a response with the function computing its value
+type trailer struct {
+	name  string
+	value func() string
 }
 
 func (r *responseBuilder) WithHeader(key, value string) ResponseBuilder {
@@ -83,8 +168,9 @@ func (r *responseBuilder) WithHeader(key, value string) ResponseBuilder {
 	return r
 }
 
+// WithStatus sets the status written when the response is sent
 func (r *responseBuilder) WithStatus(status int) ResponseBuilder {
-	r.request.Writer().WriteHeader(status)
+	r.status = status
 	return r
 }
 
@@ -98,6 +184,96 @@ func (r *responseBuilder) WithBodyFunc(bodyFunc ResponseDataFunc) ResponseBuilde
 	return r
 }
 
+func (r *responseBuilder) WithText(text string) ResponseBuilder {
+	return r.WithHeader("Content-Type", "text/plain; charset=utf-8").WithBodyFunc(StringData(text))
+}
+
+func (r *responseBuilder) WithHTML(html string) ResponseBuilder {
+	return r.WithHeader("Content-Type", "text/html; charset=utf-8").WithBodyFunc(StringData(html))
+}
+
+func (r *responseBuilder) WithJSON(v interface{}) ResponseBuilder {
+	return r.WithHeader("Content-Type", "application/json").WithBodyFunc(r.request.JSONData(v))
+}
+
+func (r *responseBuilder) WithTrailer(name string, value func() string) ResponseBuilder {
+	r.request.Writer().Header().Add("Trailer", name)
+	r.trailers = append(r.trailers, trailer{name: name, value: value})
+	return r
+}
+
+func (r *responseBuilder) Build() *Response {
+	return &Response{state: r}
+}
+
+// Responder writes the standard responses the service uses itself, so that handlers and middleware answer in the
+// same format
+type Responder interface {
+	OK(w http.ResponseWriter, r *http.Request, body interface{})
+	Created(w http.ResponseWriter, r *http.Request, location string, body interface{})
+	Accepted(w http.ResponseWriter, r *http.Request, body interface{})
+	NoContent(w http.ResponseWriter, r *http.Request)
+	SuccessResponse(w http.ResponseWriter, r *http.Request, responseCode int, body interface{})
+	BadRequest(w http.ResponseWriter, r *http.Request)
+	NotFound(w http.ResponseWriter, r *http.Request)
+	InternalServerError(w http.ResponseWriter, r *http.Request)
+	Unauthorized(w http.ResponseWriter, r *http.Request)
+	Forbidden(w http.ResponseWriter, r *http.Request)
+	MethodNotAllowed(w http.ResponseWriter, r *http.Request)
+	NotAcceptable(w http.ResponseWriter, r *http.Request)
+	Conflict(w http.ResponseWriter, r *http.Request)
+	RequestURITooLong(w http.ResponseWriter, r *http.Request)
+	UnprocessableEntity(w http.ResponseWriter, r *http.Request)
+	Gone(w http.ResponseWriter, r *http.Request)
+	TooManyRequests(w http.ResponseWriter, r *http.Request)
+	NotImplemented(w http.ResponseWriter, r *http.Request)
+	ServiceUnavailable(w http.ResponseWriter, r *http.Request)
+	GatewayTimeout(w http.ResponseWriter, r *http.Request)
+	InsufficientStorage(w http.ResponseWriter, r *http.Request)
+	LoopDetected(w http.ResponseWriter, r *http.Request)
+	NotExtended(w http.ResponseWriter, r *http.Request)
+	ErrorResponse(w http.ResponseWriter, r *http.Request, responseCode int, message string)
+}
+
+func (s *service) OK(w http.ResponseWriter, r *http.Request, body interface{}) {
+	s.SuccessResponse(w, r, http.StatusOK, body)
+}
+
+// Created responds with 201, pointing the Location header at the created resource
+func (s *service) Created(w http.ResponseWriter, r *http.Request, location string, body interface{}) {
+	if location != "" {
+		w.Header().Set("Location", location)
+	}
+	s.SuccessResponse(w, r, http.StatusCreated, body)
+}
+
+func (s *service) Accepted(w http.ResponseWriter, r *http.Request, body interface{}) {
+	s.SuccessResponse(w, r, http.StatusAccepted, body)
+}
+
+func (s *service) NoContent(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SuccessResponse responds with the status and the body encoded as JSON, or with no body when it is nil
+func (s *service) SuccessResponse(w http.ResponseWriter, r *http.Request, responseCode int, body interface{}) {
+	if body == nil {
+		w.WriteHeader(responseCode)
+		return
+	}
+	data, err := s.encodeJSON(body)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error encoding response body", "error", err)
+		s.InternalServerError(w, r)
+		return
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(responseCode)
+	_, _ = w.Write(data)
+}
+
 func (s *service) BadRequest(w http.ResponseWriter, r *http.Request) {
 	s.ErrorResponse(w, r, http.StatusBadRequest, "Bad Request")
 }
@@ -122,10 +298,24 @@ func (s *service) MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
 	s.ErrorResponse(w, r, http.StatusMethodNotAllowed, "Method Not Allowed")
 }
 
+func (s *service) NotAcceptable(w http.ResponseWriter, r *http.Request) {
+	s.ErrorResponse(w, r, http.StatusNotAcceptable, "Not Acceptable")
+}
+
 func (s *service) Conflict(w http.ResponseWriter, r *http.Request) {
 	s.ErrorResponse(w, r, http.StatusConflict, "Conflict")
 }
 
+// RequestURITooLong responds with 414, as the service does for requests longer than the limit set with
+// WithMaxURLLength
+func (s *service) RequestURITooLong(w http.ResponseWriter, r *http.Request) {
+	s.ErrorResponse(w, r, http.StatusRequestURITooLong, "Request URI Too Long")
+}
+
+func (s *service) UnprocessableEntity(w http.ResponseWriter, r *http.Request) {
+	s.ErrorResponse(w, r, http.StatusUnprocessableEntity, "Unprocessable Entity")
+}
+
 func (s *service) Gone(w http.ResponseWriter, r *http.Request) {
 	s.ErrorResponse(w, r, http.StatusGone, "Gone")
 }
@@ -163,16 +353,48 @@ func (s *service) ErrorResponse(w http.ResponseWriter, r *http.Request, response
 	http.Error(w, message, responseCode)
 }
 
+// Send writes the response. Nothing is written once the client has gone, and the error of the context the request
+// arrived with is returned. Send deliberately checks that context rather than Context(), so that a request context
+// canceled by WithRequestContextTimeout, or by Stop for a streaming route, still lets the handler send its final
+// response, such as an error reporting the timeout or a closing event.
 func (r *Response) Send() error {
-	body, err := r.state.bodyFunc()
-	if err != nil {
-		slog.ErrorContext(r.state.request.Context(), "error getting response body", err)
-		return err
+	if ctx := r.state.request.clientCtx; ctx != nil && ctx.Err() != nil {
+		slog.DebugContext(ctx, "client gone, response not sent", "error", context.Cause(ctx))
+		return ctx.Err()
 	}
-	_, err = r.state.request.Writer().Write(body)
-	if err != nil {
-		slog.ErrorContext(r.state.request.Context(), "error writing response body", err)
-		return err
+	if r.state.status != 0 {
+		r.state.request.Writer().WriteHeader(r.state.status)
+	}
+	if r.state.bodyFunc != nil {
+		body, err := r.state.bodyFunc()
+		if err != nil {
+			logWriteError(r.state.request.Context(), "error getting response body", err)
+			return err
+		}
+		_, err = r.state.request.Writer().Write(body)
+		if err != nil {
+			logWriteError(r.state.request.Context(), "error writing response body", err)
+			return err
+		}
+	}
+	for _, t := range r.state.trailers {
+		r.state.request.Writer().Header().Set(t.name, t.value())
 	}
 	return nil
 }
+
+// logWriteError logs an error writing a response, at debug level when it only means that the client has gone
+func logWriteError(ctx context.Context, msg string, err error) {
+	if isClientGone(err) {
+		slog.DebugContext(ctx, msg, "error", err)
+		return
+	}
+	slog.ErrorContext(ctx, msg, "error", err)
+}
+
+// isClientGone reports whether the error comes from writing to a client that has disconnected, or from a stream cut
+// short as it did, which is a normal occurrence rather than a failure of the service
+func isClientGone(err error) bool {
+	return errors.Is(err, net.ErrClosed) || errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, ErrStreamCanceled)
+}
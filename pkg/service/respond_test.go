@@ -0,0 +1,414 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStreamJSONArray(t *testing.T) {
+	t.Run("complete", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		request := NewRequest(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil), w)
+		ch := make(chan int, 3)
+		ch <- 1
+		ch <- 2
+		ch <- 3
+		close(ch)
+		if _, err := StreamJSONArray(context.Background(), request, ch)(); err != nil {
+			t.Fatal(err)
+		}
+		if got := w.Body.String(); got != "[1,2,3]" {
+			t.Errorf("got %s, want [1,2,3]", got)
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("got Content-Type %q, want application/json", got)
+		}
+	})
+	t.Run("canceled", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		request := NewRequest(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil), w)
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := make(chan int)
+		go func() {
+			ch <- 1
+			ch <- 2
+			cancel()
+		}()
+		_, err := StreamJSONArray(ctx, request, ch)()
+		if !errors.Is(err, ErrStreamCanceled) {
+			t.Errorf("got %v, want %v", err, ErrStreamCanceled)
+		}
+		if got := w.Body.String(); got != "[1,2]" {
+			t.Errorf("got %s, want the truncated array [1,2]", got)
+		}
+	})
+}
+
+// failingWriter is a response writer whose writes fail as they do once the client has disconnected
+type failingWriter struct {
+	http.ResponseWriter
+}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, syscall.EPIPE
+}
+
+func TestBinaryStreamDataWriteError(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	request := NewRequest(ctx, r, failingWriter{httptest.NewRecorder()})
+	ch := make(chan []byte, 1)
+	ch <- []byte("data")
+	_, err := BinaryStreamDataWithCancel(ctx, *request, ch, cancel)()
+	if !errors.Is(err, syscall.EPIPE) {
+		t.Errorf("got %v, want %v", err, syscall.EPIPE)
+	}
+	if cause := context.Cause(ctx); !errors.Is(cause, syscall.EPIPE) {
+		t.Errorf("got producer context cause %v, want %v", cause, syscall.EPIPE)
+	}
+}
+
+func TestBinaryStreamData(t *testing.T) {
+	t.Run("producer closes the channel", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		request := NewRequest(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil), w)
+		ch := make(chan []byte, 2)
+		ch <- []byte("a")
+		ch <- []byte("b")
+		close(ch)
+		errc := make(chan error, 1)
+		go func() {
+			_, err := BinaryStreamData(context.Background(), *request, ch)()
+			errc <- err
+		}()
+		select {
+		case err := <-errc:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("stream did not end when the channel was closed")
+		}
+		if got := w.Body.String(); got != "ab" {
+			t.Errorf("got %q, want ab", got)
+		}
+	})
+	t.Run("context done", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		request := NewRequest(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil), w)
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := make(chan []byte)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = BinaryStreamData(ctx, *request, ch)()
+		}()
+		ch <- []byte("a")
+		cancel()
+		<-done
+		// The channel is left to the producer, so sending on it or closing it must not panic
+		select {
+		case ch <- []byte("b"):
+		default:
+		}
+		close(ch)
+		if got := w.Body.String(); got != "a" {
+			t.Errorf("got %q, want a", got)
+		}
+	})
+}
+
+func TestSuccessHelpers(t *testing.T) {
+	s := newTestService()
+	tests := []struct {
+		name     string
+		respond  func(w http.ResponseWriter, r *http.Request)
+		code     int
+		body     string
+		location string
+	}{
+		{"OK", func(w http.ResponseWriter, r *http.Request) { s.OK(w, r, map[string]int{"a": 1}) }, http.StatusOK,
+			`{"a":1}`, ""},
+		{"Created", func(w http.ResponseWriter, r *http.Request) { s.Created(w, r, "/things/1", map[string]int{"id": 1}) },
+			http.StatusCreated, `{"id":1}`, "/things/1"},
+		{"Accepted", func(w http.ResponseWriter, r *http.Request) { s.Accepted(w, r, nil) }, http.StatusAccepted, "", ""},
+		{"NoContent", func(w http.ResponseWriter, r *http.Request) { s.NoContent(w, r) }, http.StatusNoContent, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.respond(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != tt.code || w.Body.String() != tt.body || w.Header().Get("Location") != tt.location {
+				t.Errorf("got status %d with body %q and Location %q, want %d with %q and %q", w.Code, w.Body.String(),
+					w.Header().Get("Location"), tt.code, tt.body, tt.location)
+			}
+			if tt.body != "" && w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("got Content-Type %q, want application/json", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestTrailers(t *testing.T) {
+	s := newTestService()
+	s.GET("/stream", Handler(func(r *Request) *Response {
+		sum := sha256.New()
+		return r.ResponseBuilder().WithBodyFunc(func() ([]byte, error) {
+			for _, chunk := range []string{"a", "b", "c"} {
+				sum.Write([]byte(chunk))
+				if _, err := r.Writer().Write([]byte(chunk)); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nil
+		}).WithTrailer("X-Checksum", func() string {
+			return hex.EncodeToString(sum.Sum(nil))
+		}).Build()
+	}).ServeHTTP)
+	s.GET("/direct", func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		_, _ = w.Write([]byte("x"))
+		w.(http.Flusher).Flush()
+		request.SetTrailer("X-Late", "yes")
+	})
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	checksum := sha256.Sum256([]byte("abc"))
+	tests := []struct {
+		path    string
+		body    string
+		trailer string
+		want    string
+	}{
+		{"/stream", "abc", "X-Checksum", hex.EncodeToString(checksum[:])},
+		{"/direct", "x", "X-Late", "yes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.body {
+				t.Errorf("got body %q, want %q", body, tt.body)
+			}
+			if got := resp.Trailer.Get(tt.trailer); got != tt.want {
+				t.Errorf("got trailer %s %q, want %q", tt.trailer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuilderContentTypes(t *testing.T) {
+	tests := []struct {
+		name        string
+		build       func(ResponseBuilder) ResponseBuilder
+		contentType string
+		body        string
+	}{
+		{"text", func(b ResponseBuilder) ResponseBuilder { return b.WithText("<b>hi</b>") },
+			"text/plain; charset=utf-8", "<b>hi</b>"},
+		{"HTML", func(b ResponseBuilder) ResponseBuilder { return b.WithHTML("<b>hi</b>") },
+			"text/html; charset=utf-8", "<b>hi</b>"},
+		{"JSON", func(b ResponseBuilder) ResponseBuilder { return b.WithJSON(map[string]int{"n": 1}) },
+			"application/json", `{"n":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService()
+			s.GET("/", Handler(func(r *Request) *Response {
+				return tt.build(r.ResponseBuilder().WithStatus(http.StatusCreated)).Build()
+			}).ServeHTTP)
+			w := serve(s.mux, http.MethodGet, "/", nil)
+			if w.Code != http.StatusCreated {
+				t.Errorf("got status %d, want 201", w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("got Content-Type %q, want %q", got, tt.contentType)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tt.body {
+				t.Errorf("got body %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name       string
+		clientGone bool
+		timedOut   bool
+		want       string
+		err        error
+	}{
+		{"sent", false, false, "hello", nil},
+		{"request context timed out", false, true, "hello", nil},
+		{"client gone", true, false, "", context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientCtx, cancelClient := context.WithCancel(context.Background())
+			defer cancelClient()
+			w := httptest.NewRecorder()
+			request := NewRequest(clientCtx, httptest.NewRequest(http.MethodGet, "/", nil), w)
+			if tt.timedOut {
+				ctx, cancel := context.WithTimeout(clientCtx, 0)
+				defer cancel()
+				request.setContext(ctx)
+			}
+			if tt.clientGone {
+				cancelClient()
+			}
+			err := request.ResponseBuilder().WithBody([]byte("hello")).Build().Send()
+			if !errors.Is(err, tt.err) {
+				t.Errorf("got %v, want %v", err, tt.err)
+			}
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("got body %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamsEndOnShutdown(t *testing.T) {
+	t.Run("binary", func(t *testing.T) {
+		shutdown := make(chan struct{})
+		request := NewRequest(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+		request.shutdown = shutdown
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+		close(shutdown)
+		if _, err := BinaryStreamDataWithCancel(ctx, *request, make(chan []byte), cancel)(); err != nil {
+			t.Fatal(err)
+		}
+		if cause := context.Cause(ctx); !errors.Is(cause, ErrStreamCanceled) {
+			t.Errorf("got producer context cause %v, want %v", cause, ErrStreamCanceled)
+		}
+	})
+	t.Run("JSON array", func(t *testing.T) {
+		shutdown := make(chan struct{})
+		w := httptest.NewRecorder()
+		request := NewRequest(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil), w)
+		request.shutdown = shutdown
+		close(shutdown)
+		_, err := StreamJSONArray(context.Background(), request, make(chan int))()
+		if !errors.Is(err, ErrStreamCanceled) {
+			t.Errorf("got %v, want %v", err, ErrStreamCanceled)
+		}
+		if got := w.Body.String(); got != "[]" {
+			t.Errorf("got %s, want the closed array []", got)
+		}
+	})
+}
+
+func TestRequestURITooLong(t *testing.T) {
+	var responder Responder = NewService()
+	w := httptest.NewRecorder()
+	responder.RequestURITooLong(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusRequestURITooLong {
+		t.Errorf("got status %d, want 414", w.Code)
+	}
+}
+
+func TestClientGoneLogging(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		level string
+	}{
+		{"client gone", syscall.EPIPE, "level=DEBUG"},
+		{"connection reset", syscall.ECONNRESET, "level=DEBUG"},
+		{"other error", errors.New("disk full"), "level=ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			w := erroringWriter{httptest.NewRecorder(), tt.err}
+			request := NewRequest(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil), w)
+			err := request.ResponseBuilder().WithBody([]byte("hello")).Build().Send()
+			if !errors.Is(err, tt.err) {
+				t.Errorf("got %v, want %v", err, tt.err)
+			}
+			ch := make(chan []byte, 1)
+			ch <- []byte("data")
+			if _, err := BinaryStreamData(context.Background(), *request, ch)(); !errors.Is(err, tt.err) {
+				t.Errorf("got %v from the stream, want %v", err, tt.err)
+			}
+			got := logs.String()
+			if n := strings.Count(got, tt.level); n != 2 {
+				t.Errorf("got logs %q, want both write errors at %s", got, tt.level)
+			}
+		})
+	}
+}
+
+// erroringWriter is a response writer whose writes fail with the error
+type erroringWriter struct {
+	http.ResponseWriter
+	err error
+}
+
+func (w erroringWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+func TestSendAfterRequestContextCanceled(t *testing.T) {
+	s, _ := startTestService(t, WithRequestContextTimeout(20*time.Millisecond), WithShutdownTimeout(5*time.Second))
+	final := Handler(func(r *Request) *Response {
+		<-r.Context().Done()
+		return r.ResponseBuilder().WithStatus(http.StatusOK).WithText("final").Build()
+	})
+	s.GET("/slow", final.ServeHTTP)
+	arrived := make(chan struct{})
+	s.GET("/stream", func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		final.ServeHTTP(w, r)
+	}, WithRouteTimeout(0))
+	get := func(path string) (string, error) {
+		resp, err := http.Get(s.testURL(path))
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+	t.Run("request context timed out", func(t *testing.T) {
+		if body, err := get("/slow"); err != nil || body != "final" {
+			t.Errorf("got body %q with error %v, want final", body, err)
+		}
+	})
+	t.Run("service stopping", func(t *testing.T) {
+		type result struct {
+			body string
+			err  error
+		}
+		results := make(chan result, 1)
+		go func() {
+			body, err := get("/stream")
+			results <- result{body, err}
+		}()
+		<-arrived
+		if err := s.Stop(); err != nil {
+			t.Fatal(err)
+		}
+		if got := <-results; got.err != nil || got.body != "final" {
+			t.Errorf("got body %q with error %v, want final", got.body, got.err)
+		}
+	})
+}
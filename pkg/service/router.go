@@ -0,0 +1,229 @@
+package service
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Router registers handlers for a method and path pattern. Patterns are slash separated paths whose segments may be
+// a literal, a named wildcard such as {id} matching a single segment, or a trailing {rest...} matching the remainder
+// of the path.
+type Router interface {
+	Route(method, pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	GET(pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	POST(pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	PUT(pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	PATCH(pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	DELETE(pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	// Handle registers the handler for each of the methods
+	Handle(methods []string, pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	// Any registers the handler for every method, including OPTIONS. Routes registered for a specific method take
+	// precedence over it.
+	Any(pattern string, handler http.HandlerFunc, opts ...RouteOption)
+	// VersionedRoute registers a handler per API version. Each version is served under its own prefix, such as
+	// /v2/users, and the unprefixed pattern selects the version from a vendor Accept header such as
+	// application/vnd.myapp.v2+json, defaulting to the latest version.
+	VersionedRoute(method, pattern string, versions map[string]http.HandlerFunc, opts ...RouteOption)
+	// Group returns a Router registering routes under the prefix with the middleware applied to each of them
+	Group(prefix string, middleware ...Middleware) Router
+}
+
+// anyMethod is the method of routes registered with Any
+const anyMethod = "*"
+
+// route is a handler registered for a method and path pattern
+type route struct {
+	method   string
+	pattern  string
+	segments []string
+	handler  http.Handler
+	// timeout overrides the service handler timeout when timeoutSet is true
+	timeout    time.Duration
+	timeoutSet bool
+	middleware []Middleware
+	metadata   RouteMetadata
+}
+
+// RouteOption configures a registered route
+type RouteOption func(*route)
+
+// WithRouteTimeout sets the handler timeout for the route, taking precedence over WithHandlerTimeout. A positive
+// timeout buffers the response until the handler returns, hiding http.Flusher from it. A zero timeout marks the route
+// as streaming, disabling the handler timeout and the connection write deadline for it.
+func WithRouteTimeout(timeout time.Duration) RouteOption {
+	return func(r *route) {
+		r.timeout = timeout
+		r.timeoutSet = true
+	}
+}
+
+// WithRouteMiddleware appends middleware run for the route only, after the global middleware chain
+func WithRouteMiddleware(middleware ...Middleware) RouteOption {
+	return func(r *route) {
+		r.middleware = append(r.middleware, middleware...)
+	}
+}
+
+// router matches requests against the registered routes
+type router struct {
+	mu     sync.RWMutex
+	routes []*route
+}
+
+func (rt *router) add(method, pattern string, handler http.Handler) *route {
+	r := &route{
+		method:   strings.ToUpper(method),
+		pattern:  pattern,
+		segments: splitPath(pattern),
+		handler:  handler,
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.routes = append(rt.routes, r)
+	return r
+}
+
+// match returns the route registered for the method and path along with its path parameters. A HEAD request without
+// a HEAD route of its own is matched to the GET route for the path, even when an Any route matches it too. When the
+// path matches routes registered for other methods only, the methods allowed for the path are returned so the caller
+// can respond to OPTIONS or with 405.
+func (rt *router) match(method, path string) (*route, map[string]string, []string) {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	segments := splitPath(path)
+	var best, get *route
+	bestScore, getScore := -1, -1
+	allowed := make(map[string]bool)
+	for _, r := range rt.routes {
+		score, ok := r.matchSegments(segments)
+		if !ok {
+			continue
+		}
+		if r.method != method && r.method != anyMethod {
+			allowed[r.method] = true
+			if r.method == http.MethodGet {
+				allowed[http.MethodHead] = true
+				if score > getScore {
+					get, getScore = r, score
+				}
+			}
+			continue
+		}
+		// Prefer the route with the most literal segments, then a route for the method over one registered with Any
+		if score > bestScore || score == bestScore && best.method == anyMethod {
+			best, bestScore = r, score
+		}
+	}
+	// A HEAD request prefers the GET route over a route registered with Any that is no more specific
+	if method == http.MethodHead && get != nil && (best == nil || best.method == anyMethod && getScore >= bestScore) {
+		best = get
+	}
+	if best != nil {
+		return best, best.params(segments), nil
+	}
+	if len(allowed) == 0 {
+		return nil, nil, nil
+	}
+	allowed[http.MethodOptions] = true
+	methods := make([]string, 0, len(allowed))
+	for m := range allowed {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	return nil, nil, methods
+}
+
+// matchSegments reports whether the path segments match the route pattern, scoring the match by the number of
+// literal segments it contains
+func (r *route) matchSegments(segments []string) (int, bool) {
+	score := 0
+	for i, pattern := range r.segments {
+		if isRemainderWildcard(pattern) && i == len(r.segments)-1 {
+			return score, len(segments) >= i
+		}
+		if i >= len(segments) {
+			return 0, false
+		}
+		switch {
+		case isWildcard(pattern):
+			if segments[i] == "" {
+				return 0, false
+			}
+		case pattern == segments[i]:
+			score++
+		default:
+			return 0, false
+		}
+	}
+	return score, len(segments) == len(r.segments)
+}
+
+// params extracts the wildcard values from path segments matched by the route
+func (r *route) params(segments []string) map[string]string {
+	params := make(map[string]string)
+	for i, pattern := range r.segments {
+		switch {
+		case isRemainderWildcard(pattern):
+			params[wildcardName(pattern)] = strings.Join(segments[i:], "/")
+		case isWildcard(pattern):
+			params[wildcardName(pattern)] = segments[i]
+		}
+	}
+	return params
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+func isWildcard(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func isRemainderWildcard(segment string) bool {
+	return isWildcard(segment) && strings.HasSuffix(segment, "...}")
+}
+
+func wildcardName(segment string) string {
+	return strings.TrimSuffix(strings.Trim(segment, "{}"), "...")
+}
+
+func (s *service) Route(method, pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	r := s.routes.add(method, pattern, handler)
+	for _, opt := range opts {
+		opt(r)
+	}
+}
+
+func (s *service) GET(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.Route(http.MethodGet, pattern, handler, opts...)
+}
+
+func (s *service) POST(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.Route(http.MethodPost, pattern, handler, opts...)
+}
+
+func (s *service) PUT(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.Route(http.MethodPut, pattern, handler, opts...)
+}
+
+func (s *service) PATCH(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.Route(http.MethodPatch, pattern, handler, opts...)
+}
+
+func (s *service) DELETE(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.Route(http.MethodDelete, pattern, handler, opts...)
+}
+
+func (s *service) Handle(methods []string, pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	for _, method := range methods {
+		s.Route(method, pattern, handler, opts...)
+	}
+}
+
+func (s *service) Any(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	s.Route(anyMethod, pattern, handler, opts...)
+}
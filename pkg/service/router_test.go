@@ -0,0 +1,166 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// traceMiddleware returns middleware adding its name to the X-Trace response header
+func traceMiddleware(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeBody returns a handler writing the body
+func writeBody(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func TestRouting(t *testing.T) {
+	s := newTestService()
+	s.GET("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		_, _ = w.Write([]byte("get " + request.PathValue("id")))
+	})
+	s.GET("/items/special", writeBody("special"))
+	s.DELETE("/items/{id}", writeBody("delete"))
+	s.GET("/files/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		_, _ = w.Write([]byte(request.PathValue("path")))
+	})
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"wildcard", http.MethodGet, "/items/7", http.StatusOK, "get 7"},
+		{"literal over wildcard", http.MethodGet, "/items/special", http.StatusOK, "special"},
+		{"method", http.MethodDelete, "/items/7", http.StatusOK, "delete"},
+		{"remainder", http.MethodGet, "/files/a/b/c", http.StatusOK, "a/b/c"},
+		{"empty segment", http.MethodGet, "/items/", http.StatusNotFound, ""},
+		{"unrouted", http.MethodGet, "/other", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(s.mux, tt.method, tt.path, nil)
+			if w.Code != tt.wantCode {
+				t.Fatalf("got status %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("got body %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+	w := serve(s.mux, http.MethodPost, "/items/7", nil)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("got status %d for an unregistered method, want 405", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "DELETE, GET, HEAD, OPTIONS" {
+		t.Errorf("got Allow %q, want %q", allow, "DELETE, GET, HEAD, OPTIONS")
+	}
+}
+
+func TestRouteMiddleware(t *testing.T) {
+	requireAuth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	s := newTestService(WithMiddleware(traceMiddleware("global")))
+	s.GET("/admin", writeBody("admin"), WithRouteMiddleware(traceMiddleware("route"), requireAuth))
+	s.GET("/public", writeBody("public"))
+	tests := []struct {
+		path   string
+		header map[string]string
+		code   int
+		trace  string
+	}{
+		{"/admin", nil, http.StatusUnauthorized, "global,route"},
+		{"/admin", map[string]string{"Authorization": "Bearer token"}, http.StatusOK, "global,route"},
+		{"/public", nil, http.StatusOK, "global"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := serve(s.mux, http.MethodGet, tt.path, tt.header)
+			if w.Code != tt.code {
+				t.Errorf("got status %d, want %d", w.Code, tt.code)
+			}
+			if trace := strings.Join(w.Header().Values("X-Trace"), ","); trace != tt.trace {
+				t.Errorf("got middleware %s, want %s", trace, tt.trace)
+			}
+		})
+	}
+}
+
+func TestAutomaticHeadAndOptions(t *testing.T) {
+	s := newTestService()
+	s.GET("/items", writeBody("get"))
+	s.POST("/items", writeBody("post"))
+	s.Route(http.MethodHead, "/explicit", writeBody("head"))
+	s.GET("/explicit", writeBody("get"))
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		body   string
+		allow  string
+	}{
+		{"HEAD served by GET", http.MethodHead, "/items", http.StatusOK, "get", ""},
+		{"HEAD route", http.MethodHead, "/explicit", http.StatusOK, "head", ""},
+		{"OPTIONS", http.MethodOptions, "/items", http.StatusNoContent, "", "GET, HEAD, OPTIONS, POST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(s.mux, tt.method, tt.path, nil)
+			if w.Code != tt.code || w.Body.String() != tt.body {
+				t.Errorf("got status %d with body %q, want %d with %q", w.Code, w.Body.String(), tt.code, tt.body)
+			}
+			if allow := w.Header().Get("Allow"); allow != tt.allow {
+				t.Errorf("got Allow %q, want %q", allow, tt.allow)
+			}
+		})
+	}
+}
+
+func TestHandleAndAny(t *testing.T) {
+	s := newTestService()
+	s.Handle([]string{http.MethodPut, http.MethodPatch}, "/items", writeBody("update"))
+	s.Any("/items", writeBody("any"))
+	s.GET("/items", writeBody("get"))
+	s.Any("/anything", writeBody("any"))
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPut, "/items", "update"},
+		{http.MethodPatch, "/items", "update"},
+		{http.MethodGet, "/items", "get"},
+		{http.MethodHead, "/items", "get"},
+		{http.MethodDelete, "/items", "any"},
+		{http.MethodOptions, "/items", "any"},
+		{http.MethodHead, "/anything", "any"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := serve(s.mux, tt.method, tt.path, nil)
+			if w.Code != http.StatusOK || w.Body.String() != tt.body {
+				t.Errorf("got status %d with body %q, want 200 with %q", w.Code, w.Body.String(), tt.body)
+			}
+		})
+	}
+}
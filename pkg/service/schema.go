@@ -0,0 +1,80 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"net/http"
+	"strings"
+)
+
+// WithRequestSchema validates the JSON body of requests to the route against the compiled JSON Schema, such as one
+// compiled from an OpenAPI component with jsonschema.CompileString, before the handler runs. Malformed bodies are
+// rejected with 400, and bodies violating the schema with 422 and a JSON ValidationError whose violations name the
+// failing values by JSON pointer.
+func WithRequestSchema(schema *jsonschema.Schema) RouteOption {
+	return WithRouteMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request, ok := RequestFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			err := validateSchema(request, schema)
+			if err == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				status := StatusFromError(err)
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+			data, err := request.options.encodeJSON(validationErr)
+			if err != nil {
+				request.Logger().ErrorContext(request.Context(), "error encoding response body", "error", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write(data)
+		})
+	})
+}
+
+// validateSchema validates the request body against the schema. Malformed bodies are reported as ErrBind and
+// violations as a *ValidationError.
+func validateSchema(request *Request, schema *jsonschema.Schema) error {
+	body, err := request.Body()
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// The schema validator expects numbers as json.Number to check them without loss of precision
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return fmt.Errorf("%w: %v", ErrBind, err)
+	}
+	var schemaErr *jsonschema.ValidationError
+	if err := schema.Validate(v); !errors.As(err, &schemaErr) {
+		return err
+	}
+	return &ValidationError{Violations: schemaViolations(schemaErr, nil)}
+}
+
+// schemaViolations flattens the causes of a schema validation error into violations
+func schemaViolations(err *jsonschema.ValidationError, violations []FieldViolation) []FieldViolation {
+	if len(err.Causes) == 0 {
+		rule := err.KeywordLocation[strings.LastIndex(err.KeywordLocation, "/")+1:]
+		return append(violations, FieldViolation{Field: err.InstanceLocation, Rule: rule, Message: err.Message})
+	}
+	for _, cause := range err.Causes {
+		violations = schemaViolations(cause, violations)
+	}
+	return violations
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"encoding/json"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRequestSchema(t *testing.T) {
+	schema := jsonschema.MustCompileString("user.json", `{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string"},
+			"age": {"type": "integer", "minimum": 0}
+		}
+	}`)
+	s := newTestService()
+	s.POST("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}, WithRequestSchema(schema))
+	tests := []struct {
+		name   string
+		body   string
+		code   int
+		fields []string
+	}{
+		{"valid", `{"name": "alice", "age": 30}`, http.StatusCreated, nil},
+		{"malformed", `{"name":`, http.StatusBadRequest, nil},
+		{"violations", `{"age": -1}`, http.StatusUnprocessableEntity, []string{"", "/age"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post(s.mux, "/users", tt.body, map[string]string{"Content-Type": "application/json"})
+			if w.Code != tt.code {
+				t.Fatalf("got status %d, want %d", w.Code, tt.code)
+			}
+			if tt.fields == nil {
+				return
+			}
+			var got ValidationError
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			fields := make([]string, 0, len(got.Violations))
+			for _, violation := range got.Violations {
+				fields = append(fields, violation.Field)
+			}
+			sort.Strings(fields)
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("got violations of %q, want %q", fields, tt.fields)
+			}
+		})
+	}
+}
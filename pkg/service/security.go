@@ -0,0 +1,117 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SecurityHeaders configures the security headers added to every response. An empty value omits the header.
+type SecurityHeaders struct {
+	// ContentTypeOptions is the X-Content-Type-Options header
+	ContentTypeOptions string
+	// FrameOptions is the X-Frame-Options header
+	FrameOptions string
+	// ReferrerPolicy is the Referrer-Policy header
+	ReferrerPolicy string
+	// StrictTransportSecurity is the Strict-Transport-Security header, only sent over TLS
+	StrictTransportSecurity string
+	// ContentSecurityPolicy is the Content-Security-Policy header
+	ContentSecurityPolicy string
+}
+
+// DefaultSecurityHeaders returns the recommended security headers. No Content-Security-Policy is set, as a useful
+// policy depends on the application.
+func DefaultSecurityHeaders() SecurityHeaders {
+	return SecurityHeaders{
+		ContentTypeOptions:      "nosniff",
+		FrameOptions:            "DENY",
+		ReferrerPolicy:          "strict-origin-when-cross-origin",
+		StrictTransportSecurity: "max-age=63072000; includeSubDomains",
+	}
+}
+
+// WithSecurityHeaders adds the configured security headers to every response
+func WithSecurityHeaders(securityHeaders SecurityHeaders) Option {
+	return func(o *Options) {
+		o.securityHeaders = &securityHeaders
+	}
+}
+
+func securityHeadersMiddleware(cfg SecurityHeaders) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			setHeader(w, "X-Content-Type-Options", cfg.ContentTypeOptions)
+			setHeader(w, "X-Frame-Options", cfg.FrameOptions)
+			setHeader(w, "Referrer-Policy", cfg.ReferrerPolicy)
+			setHeader(w, "Content-Security-Policy", cfg.ContentSecurityPolicy)
+			if r.TLS != nil {
+				setHeader(w, "Strict-Transport-Security", cfg.StrictTransportSecurity)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setHeader sets the response header unless the value is empty
+func setHeader(w http.ResponseWriter, key, value string) {
+	if value != "" {
+		w.Header().Set(key, value)
+	}
+}
+
+// WithForceHTTPS redirects plaintext requests to their HTTPS equivalent with 308 Permanent Redirect. Requests are
+// considered secure when they arrived over TLS or, from a proxy trusted with WithTrustedProxies, when it forwarded
+// https as the scheme in the Forwarded or X-Forwarded-Proto header.
+func WithForceHTTPS(forceHTTPS bool) Option {
+	return func(o *Options) {
+		o.forceHTTPS = forceHTTPS
+	}
+}
+
+func (s *service) forceHTTPSMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.isSecure(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		http.Redirect(w, r, "https://"+r.Host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// isSecure reports whether the client connected over HTTPS, directly or through a TLS terminating proxy. The
+// forwarded scheme is only honoured from trusted proxies, as any client can send the headers.
+func (o *Options) isSecure(r *http.Request) bool {
+	if o != nil && o.proxyHeaders {
+		// The forwarded scheme has already been applied to the URL, or the headers removed for untrusted peers
+		if r.URL.Scheme != "" {
+			return r.URL.Scheme == "https"
+		}
+		return r.TLS != nil
+	}
+	if peer, err := parseHostAddr(r.RemoteAddr); err == nil {
+		if _, proto, _ := o.resolveForwarded(peer, r.Header); proto != "" {
+			return strings.EqualFold(proto, "https")
+		}
+	}
+	return r.TLS != nil
+}
+
+// WithServerHeader sets the Server header on every response. An empty value ensures the header is absent.
+func WithServerHeader(serverHeader string) Option {
+	return func(o *Options) {
+		o.serverHeader = &serverHeader
+	}
+}
+
+func serverHeaderMiddleware(serverHeader string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if serverHeader == "" {
+				w.Header().Del("Server")
+			} else {
+				w.Header().Set("Server", serverHeader)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
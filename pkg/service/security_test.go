@@ -0,0 +1,112 @@
+package service
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	cfg := DefaultSecurityHeaders()
+	cfg.ContentSecurityPolicy = "default-src 'self'"
+	cfg.FrameOptions = ""
+	s := newTestService(WithSecurityHeaders(cfg))
+	s.GET("/", writeBody("ok"))
+	tests := []struct {
+		name   string
+		tls    bool
+		header string
+		want   string
+	}{
+		{"content type options", false, "X-Content-Type-Options", "nosniff"},
+		{"content security policy", false, "Content-Security-Policy", "default-src 'self'"},
+		{"omitted frame options", false, "X-Frame-Options", ""},
+		{"no HSTS over plaintext", false, "Strict-Transport-Security", ""},
+		{"HSTS over TLS", true, "Strict-Transport-Security", cfg.StrictTransportSecurity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			w := httptest.NewRecorder()
+			s.mux.ServeHTTP(w, r)
+			if got := w.Header().Get(tt.header); got != tt.want {
+				t.Errorf("got %s %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForceHTTPS(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		remote   string
+		proto    string
+		tls      bool
+		location string
+	}{
+		{"plaintext", nil, "1.2.3.4:5000", "", false, "https://example.com/a?x=1"},
+		{"TLS", nil, "1.2.3.4:5000", "", true, ""},
+		{"untrusted forwarded https", nil, "1.2.3.4:5000", "https", false, "https://example.com/a?x=1"},
+		{"trusted forwarded https", []Option{WithTrustedProxies([]string{"10.0.0.1"})}, "10.0.0.1:5000", "https", false, ""},
+		{"trusted forwarded http", []Option{WithTrustedProxies([]string{"10.0.0.1"})}, "10.0.0.1:5000", "http", true,
+			"https://example.com/a?x=1"},
+		{"proxy headers from untrusted peer", []Option{WithProxyHeaders(true)}, "1.2.3.4:5000", "https", false,
+			"https://example.com/a?x=1"},
+		{"proxy headers from trusted peer", []Option{WithProxyHeaders(true), WithTrustedProxies([]string{"10.0.0.1"})},
+			"10.0.0.1:5000", "https", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(append(tt.opts, WithForceHTTPS(true))...)
+			s.GET("/a", writeBody("ok"))
+			r := httptest.NewRequest(http.MethodGet, "/a?x=1", nil)
+			r.Host = "example.com"
+			r.RemoteAddr = tt.remote
+			if tt.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if tt.location == "" {
+				if w.Code != http.StatusOK {
+					t.Errorf("got status %d, want %d", w.Code, http.StatusOK)
+				}
+				return
+			}
+			if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != tt.location {
+				t.Errorf("got status %d to %q, want %d to %q", w.Code, w.Header().Get("Location"),
+					http.StatusPermanentRedirect, tt.location)
+			}
+		})
+	}
+}
+
+func TestServerHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"set", []Option{WithServerHeader("simple/1.0")}, []string{"simple/1.0"}},
+		{"suppressed", []Option{WithServerHeader("")}, nil},
+		{"unset", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(tt.opts...)
+			s.GET("/", writeBody("ok"))
+			got := serve(s.mux, http.MethodGet, "/", nil).Header().Values("Server")
+			if len(got) != len(tt.want) || len(got) > 0 && got[0] != tt.want[0] {
+				t.Errorf("got Server %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
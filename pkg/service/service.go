@@ -3,29 +3,127 @@ package service
 import (
 	"context"
 	"crypto/tls"
+	"errors"
+	"expvar"
 	"fmt"
 	"github.com/bchisham/collections-go/sequence"
+	"github.com/google/uuid"
+	"github.com/gorilla/sessions"
+	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Service interface {
-	Start()
-	Stop()
+	Router
+	Responder
+	Start() error
+	StartWithSignals(signals ...os.Signal) error
+	Stop() error
+	Ping(ctx context.Context) error
+	// OpenAPI returns an OpenAPI 3 document describing the registered routes
+	OpenAPI() ([]byte, error)
+	// Routes returns the registered routes with their metadata
+	Routes() []RouteInfo
+	// ListenerFile returns a duplicate of the listener's file descriptor for hand-off to a restarted process
+	ListenerFile() (*os.File, error)
 }
 
 type Options struct {
-	hostname              string
-	port                  int
-	requireTLS            bool
-	requestTimeout        time.Duration
-	certFile              string
-	keyFile               string
-	sessionKey            []byte
-	disableOptionsHandler bool
-	disableHealthHandler  bool
+	hostname                    string
+	port                        int
+	requireTLS                  bool
+	requestTimeout              time.Duration
+	certFile                    string
+	keyFile                     string
+	sessionKey                  []byte
+	disableOptionsHandler       bool
+	disableHealthHandler        bool
+	enablePprof                 bool
+	pprofMiddleware             []Middleware
+	enableExpvar                bool
+	emptyJSONBody               string
+	methodOverride              bool
+	middleware                  []Middleware
+	slowRequestThreshold        time.Duration
+	largeResponseThreshold      int64
+	handlerTimeout              time.Duration
+	trustedProxies              []string
+	trustedPrefixes             []netip.Prefix
+	securityHeaders             *SecurityHeaders
+	forceHTTPS                  bool
+	sessionStore                sessions.Store
+	echoPath                    string
+	interceptors                []Interceptor
+	shutdownHooks               []ShutdownHook
+	shutdownTimeout             time.Duration
+	serverHeader                *string
+	bodyLogging                 *BodyLogging
+	maxHeaderBytes              int
+	disableKeepAlives           bool
+	connState                   func(net.Conn, http.ConnState)
+	errorLog                    *slog.Logger
+	responseBuffering           bool
+	responseBufferLimit         int64
+	decompressRequests          bool
+	maxRequestBody              int64
+	trailingSlash               TrailingSlashMode
+	validator                   Validator
+	maxConcurrentRequests       int
+	concurrencyWaitTimeout      time.Duration
+	concurrencyExcludeStreaming bool
+	requestContextTimeout       time.Duration
+	accessLogFormat             AccessLogFormat
+	accessLogWriter             io.Writer
+	recoverHandler              RecoverHandler
+	idGenerator                 func() uuid.UUID
+	tlsNextProtos               []string
+	hostCertificates            map[string]*tls.Certificate
+	idempotencyStore            IdempotencyStore
+	idempotencyTTL              time.Duration
+	idempotencyScope            IdempotencyScope
+	defaultHandler              http.Handler
+	gracePeriod                 time.Duration
+	listener                    net.Listener
+	http3                       bool
+	proxyHeaders                bool
+	externalBaseURL             string
+	baseURL                     *url.URL
+	readinessGate               *readinessGate
+	batchPath                   string
+	panicStackInResponse        bool
+	disableContentSniffing      bool
+	webSockets                  []webSocketRoute
+	webSocketOrigins            []string
+	readinessChecks             []*healthCheck
+	healthCheckTimeout          time.Duration
+	healthCheckCacheTTL         time.Duration
+	compression                 bool
+	compressibleTypes           []string
+	jsonEncoder                 JSONEncoder
+	requestFilters              []RequestFilter
+	maxURLLength                int
+	shutdownContext             context.Context
+	openAPIPath                 string
+	openAPITitle                string
+	openAPIVersion              string
+	routesPath                  string
+	tlsSessionTicketsDisabled   bool
+	tlsClientSessionCacheSize   int
+	embeddedFSs                 []embeddedFS
+	maxConnsPerIP               int
+	requestTransforms           []BodyTransform
+	responseTransforms          []BodyTransform
+	readHeaderTimeout           *time.Duration
 }
 
 type Option func(*Options)
@@ -67,6 +165,115 @@ func WithKeyFile(keyFile string) Option {
 	}
 }
 
+// WithMaxHeaderBytes limits the size of request headers, overriding the http.DefaultMaxHeaderBytes of 1MB
+func WithMaxHeaderBytes(maxHeaderBytes int) Option {
+	return func(o *Options) {
+		o.maxHeaderBytes = maxHeaderBytes
+	}
+}
+
+// DefaultReadHeaderTimeout is the time allowed to read the headers of a request, unless the request timeout is
+// shorter
+const DefaultReadHeaderTimeout = 10 * time.Second
+
+// WithReadHeaderTimeout limits the time allowed to read the headers of a request, separately from the request
+// timeout covering the whole request, so that clients sending headers slowly to hold connections open are cut off.
+// It defaults to DefaultReadHeaderTimeout, or to the request timeout when that is shorter. Zero leaves the headers
+// covered by the request timeout alone.
+func WithReadHeaderTimeout(readHeaderTimeout time.Duration) Option {
+	return func(o *Options) {
+		o.readHeaderTimeout = &readHeaderTimeout
+	}
+}
+
+// effectiveReadHeaderTimeout returns the read header timeout set with WithReadHeaderTimeout, or the default capped at
+// the request timeout
+func (o Options) effectiveReadHeaderTimeout() time.Duration {
+	if o.readHeaderTimeout != nil {
+		return *o.readHeaderTimeout
+	}
+	if o.requestTimeout > 0 && o.requestTimeout < DefaultReadHeaderTimeout {
+		return o.requestTimeout
+	}
+	return DefaultReadHeaderTimeout
+}
+
+// WithKeepAlivesDisabled closes each connection after its response instead of keeping it alive for reuse
+func WithKeepAlivesDisabled(disableKeepAlives bool) Option {
+	return func(o *Options) {
+		o.disableKeepAlives = disableKeepAlives
+	}
+}
+
+// WithConnState sets a callback invoked as client connections change state, for connection level observability
+func WithConnState(connState func(net.Conn, http.ConnState)) Option {
+	return func(o *Options) {
+		o.connState = connState
+	}
+}
+
+// WithIDGenerator replaces uuid.New as the generator of request IDs, for example to make them reproducible in tests
+func WithIDGenerator(idGenerator func() uuid.UUID) Option {
+	return func(o *Options) {
+		o.idGenerator = idGenerator
+	}
+}
+
+// WithTLSNextProtos sets the protocols offered through TLS ALPN in order of preference. Leaving out h2 disables
+// HTTP/2.
+func WithTLSNextProtos(tlsNextProtos []string) Option {
+	return func(o *Options) {
+		o.tlsNextProtos = tlsNextProtos
+	}
+}
+
+// WithTLSSessionTickets enables or disables TLS session resumption through session tickets, which is enabled by
+// default. Resumption lets returning clients skip the full handshake.
+func WithTLSSessionTickets(tlsSessionTickets bool) Option {
+	return func(o *Options) {
+		o.tlsSessionTicketsDisabled = !tlsSessionTickets
+	}
+}
+
+// WithTLSClientSessionCacheSize caches up to size TLS sessions for connections the service makes itself, such as the
+// loopback connection of Ping, so that they resume rather than repeat the full handshake
+func WithTLSClientSessionCacheSize(size int) Option {
+	return func(o *Options) {
+		o.tlsClientSessionCacheSize = size
+	}
+}
+
+// WithDefaultHandler sets the handler for requests matching no route, which responds with 404 by default
+func WithDefaultHandler(defaultHandler http.Handler) Option {
+	return func(o *Options) {
+		o.defaultHandler = defaultHandler
+	}
+}
+
+// WithListener serves on the listener instead of listening on the hostname and port, for example to serve on an
+// ephemeral port bound ahead of time
+func WithListener(listener net.Listener) Option {
+	return func(o *Options) {
+		o.listener = listener
+	}
+}
+
+// listenerAddr returns the address of the listener set with WithListener when it is a TCP listener
+func (o Options) listenerAddr() (*net.TCPAddr, bool) {
+	if o.listener == nil {
+		return nil, false
+	}
+	addr, ok := o.listener.Addr().(*net.TCPAddr)
+	return addr, ok
+}
+
+// WithErrorLog routes errors logged by the server, such as TLS handshake failures, to the logger at error level
+func WithErrorLog(errorLog *slog.Logger) Option {
+	return func(o *Options) {
+		o.errorLog = errorLog
+	}
+}
+
 func WithDisableOptionsHandler(disableOptionsHandler bool) Option {
 	return func(o *Options) {
 		o.disableOptionsHandler = disableOptionsHandler
@@ -79,34 +286,142 @@ func WithDisableHealthHandler(disableHealthHandler bool) Option {
 	}
 }
 
+// WithPprof registers the net/http/pprof handlers under /debug/pprof/. It is disabled by default. The handlers bypass
+// the middleware chain, so guard them with WithPprofMiddleware when the port is reachable by untrusted clients.
+func WithPprof(enablePprof bool) Option {
+	return func(o *Options) {
+		o.enablePprof = enablePprof
+	}
+}
+
+// WithExpvar serves the published expvar variables at /debug/vars. It is disabled by default.
+func WithExpvar(enableExpvar bool) Option {
+	return func(o *Options) {
+		o.enableExpvar = enableExpvar
+	}
+}
+
+// WithEmptyJSONBody sets the body, such as "{}" or "null", written for JSON responses whose handler wrote no body.
+// Responses with status 204 or 304 are left empty. An empty string disables the behaviour.
+func WithEmptyJSONBody(emptyJSONBody string) Option {
+	return func(o *Options) {
+		o.emptyJSONBody = emptyJSONBody
+	}
+}
+
+// WithMethodOverride lets POST requests select PUT, PATCH or DELETE through the X-HTTP-Method-Override header
+func WithMethodOverride(methodOverride bool) Option {
+	return func(o *Options) {
+		o.methodOverride = methodOverride
+	}
+}
+
+// WithSlowRequestThreshold logs requests taking longer than the threshold at warn level
+func WithSlowRequestThreshold(slowRequestThreshold time.Duration) Option {
+	return func(o *Options) {
+		o.slowRequestThreshold = slowRequestThreshold
+	}
+}
+
+// WithLargeResponseThreshold logs responses with bodies larger than the threshold, in bytes, at warn level
+func WithLargeResponseThreshold(largeResponseThreshold int64) Option {
+	return func(o *Options) {
+		o.largeResponseThreshold = largeResponseThreshold
+	}
+}
+
+// WithHandlerTimeout bounds the time a handler may take to respond. See WithRouteTimeout for per-route timeouts. The
+// response is buffered until the handler returns and cannot be flushed, so streaming routes need WithRouteTimeout(0).
+func WithHandlerTimeout(handlerTimeout time.Duration) Option {
+	return func(o *Options) {
+		o.handlerTimeout = handlerTimeout
+	}
+}
+
 type service struct {
 	Options
-	ctx        context.Context
-	cancelFunc context.CancelFunc
-	srv        *http.Server
-	mux        *http.ServeMux
+	mu          sync.Mutex
+	accessLogMu sync.Mutex
+	ctx         context.Context
+	cancelFunc  context.CancelFunc
+	srv         *http.Server
+	mux         *http.ServeMux
+	routes      router
+	handler     http.Handler
+	// started is set while the service context exists, and is read without the lock, which Stop holds while draining
+	started     atomic.Bool
+	draining    atomic.Bool
+	stopped     atomic.Bool
+	h3          http3Server
+	serving     net.Listener
+	idempotency Middleware
+	// streamShutdown is closed when Stop begins draining, telling stream helpers to end their streams
+	streamShutdown chan struct{}
+	// clientSessions is the TLS session cache for connections the service makes itself, such as Ping
+	clientSessions tls.ClientSessionCache
 }
 
 func NewService(opts ...Option) Service {
 	options := Options{
-		hostname:       "localhost",
-		port:           8080,
-		requireTLS:     false,
-		requestTimeout: 30 * time.Second,
+		hostname:            "localhost",
+		port:                8080,
+		requireTLS:          false,
+		requestTimeout:      30 * time.Second,
+		shutdownTimeout:     30 * time.Second,
+		responseBufferLimit: DefaultResponseBufferLimit,
+		healthCheckTimeout:  DefaultHealthCheckTimeout,
+		healthCheckCacheTTL: DefaultHealthCheckCacheTTL,
 	}
 
 	_ = sequence.FromSlice(opts).Each(func(opt Option) error {
 		opt(&options)
 		return nil
 	})
+	trustedPrefixes, err := parsePrefixes(options.trustedProxies)
+	if err != nil {
+		log.Fatal(err)
+	}
+	options.trustedPrefixes = trustedPrefixes
+	baseURL, err := parseBaseURL(options.externalBaseURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	options.baseURL = baseURL
 	srv, err := options.buildServer()
 	if err != nil {
 		log.Fatal(err)
 	}
-	return &service{
-		Options: options,
-		srv:     srv,
+	s := &service{
+		Options:        options,
+		srv:            srv,
+		streamShutdown: make(chan struct{}),
+		clientSessions: clientSessionCache(srv),
+	}
+	if s.idempotencyStore != nil {
+		s.idempotency = s.idempotencyMiddleware()
+	}
+	s.handler = s.buildHandler()
+	s.mux = s.buildMux()
+	for _, ws := range s.webSockets {
+		s.GET(ws.pattern, s.webSocketHandler(ws.handler), WithRouteTimeout(0))
 	}
+	for _, e := range s.embeddedFSs {
+		s.GET(e.prefix+"/{path...}", e.handler())
+	}
+	srv.Handler = s.mux
+	srv.BaseContext = s.baseContext
+	return s
+}
+
+// baseContext derives request contexts from the service context, so that requests still running once Stop has
+// drained the server are canceled
+func (s *service) baseContext(net.Listener) context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
 }
 
 func (o Options) hostAddr() string {
@@ -119,8 +434,17 @@ func (o Options) buildServer() (*http.Server, error) {
 		DisableGeneralOptionsHandler: o.disableOptionsHandler,
 		Addr:                         o.hostAddr(),
 		ReadTimeout:                  o.requestTimeout,
+		ReadHeaderTimeout:            o.effectiveReadHeaderTimeout(),
 		WriteTimeout:                 o.requestTimeout,
 		IdleTimeout:                  o.requestTimeout,
+		MaxHeaderBytes:               o.maxHeaderBytes,
+		ConnState:                    o.connState,
+	}
+	if o.errorLog != nil {
+		server.ErrorLog = slog.NewLogLogger(o.errorLog.Handler(), slog.LevelError)
+	}
+	if o.disableKeepAlives {
+		server.SetKeepAlivesEnabled(false)
 	}
 	if o.requireTLS {
 		tlsConfig, err := o.buildTLSConfig()
@@ -128,59 +452,168 @@ func (o Options) buildServer() (*http.Server, error) {
 			return nil, err
 		}
 		server.TLSConfig = tlsConfig
+		if len(o.tlsNextProtos) > 0 && !slices.Contains(o.tlsNextProtos, "h2") {
+			// A non-nil TLSNextProto stops the server from enabling HTTP/2 on its own
+			server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+		}
 	}
 	return server, nil
 }
 
+func (s *service) buildMux() *http.ServeMux {
+	// Build the request multiplexer
+	mux := http.NewServeMux()
+	mux.Handle("/", s)
+	if !s.disableHealthHandler {
+		mux.HandleFunc("/health", handleHealth)
+		mux.HandleFunc("/livez", handleLive)
+		mux.HandleFunc("/readyz", s.handleReady)
+		mux.HandleFunc("/drainz", s.handleDrain)
+	}
+	if s.enablePprof {
+		registerPprof(mux, s.pprofMiddleware)
+	}
+	if s.enableExpvar {
+		mux.Handle("/debug/vars", expvar.Handler())
+	}
+	if s.echoPath != "" {
+		mux.HandleFunc(s.echoPath, s.handleEcho)
+	}
+	if s.batchPath != "" {
+		mux.HandleFunc(s.batchPath, s.handleBatch)
+	}
+	if s.openAPIPath != "" {
+		mux.HandleFunc(s.openAPIPath, s.handleOpenAPI)
+	}
+	if s.routesPath != "" {
+		mux.HandleFunc(s.routesPath, s.handleRoutes)
+	}
+	return mux
+}
+
 func (o Options) buildTLSConfig() (*tls.Config, error) {
 	// Build the TLS configuration
-	cert, err := os.ReadFile(o.certFile)
-	if err != nil {
-		return nil, err
+	config := &tls.Config{NextProtos: o.tlsNextProtos}
+	if o.certFile != "" || o.keyFile != "" {
+		cert, err := os.ReadFile(o.certFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err := os.ReadFile(o.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		certificate, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
 	}
-	key, err := os.ReadFile(o.keyFile)
-	if err != nil {
-		return nil, err
+	if len(o.hostCertificates) > 0 {
+		config.GetCertificate = o.certificateForHost
 	}
-	certificate, err := tls.X509KeyPair(cert, key)
-	return &tls.Config{
-		Certificates: []tls.Certificate{certificate},
-	}, nil
+	config.SessionTicketsDisabled = o.tlsSessionTicketsDisabled
+	if o.tlsClientSessionCacheSize > 0 {
+		config.ClientSessionCache = tls.NewLRUClientSessionCache(o.tlsClientSessionCacheSize)
+	}
+	return config, nil
 }
 
-func (s *service) Start() {
+func (s *service) Start() error {
 	// Start the service
+	s.mu.Lock()
 	if s.ctx != nil {
-		log.Fatal("Service already started")
+		s.mu.Unlock()
+		return ErrServiceStarted
 	}
-	s.ctx = context.Background()
-	s.ctx, s.cancelFunc = context.WithCancel(s.ctx)
-	s.mux = http.NewServeMux()
-	s.mux.Handle("/", s)
-	if !s.disableHealthHandler {
-		s.mux.HandleFunc("/health", handleHealth)
+	// Stop holds the lock until it has finished, so a service being stopped is seen as stopped here
+	if s.stopped.Load() {
+		s.mu.Unlock()
+		return ErrServiceStopped
 	}
-	if s.requireTLS {
-		if err := s.srv.ListenAndServeTLS(s.certFile, s.keyFile); err != nil {
-			log.Fatal(err)
-		}
-	} else {
-		err := s.srv.ListenAndServe()
-		if err != nil {
-			log.Fatal(err)
+	s.ctx, s.cancelFunc = context.WithCancel(context.Background())
+	if s.http3 {
+		if err := s.startHTTP3(); err != nil {
+			s.cancelFunc()
+			s.ctx = nil
+			s.mu.Unlock()
+			return err
 		}
 	}
+	s.started.Store(true)
+	s.mu.Unlock()
+	defer s.stopOnShutdownContext()()
+	listener, err := s.listen()
+	switch {
+	case err != nil:
+	case s.requireTLS:
+		err = s.srv.ServeTLS(listener, s.certFile, s.keyFile)
+	default:
+		err = s.srv.Serve(listener)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	// The server failed to start, so allow it to be started again
+	s.mu.Lock()
+	_ = s.stopHTTP3()
+	s.cancelFunc()
+	s.ctx = nil
+	s.started.Store(false)
+	s.serving = nil
+	s.mu.Unlock()
+	return err
+}
 
+// listen returns the listener set with WithListener, or listens on the hostname and port, capping the connections
+// per client IP when a cap is set
+func (s *service) listen() (net.Listener, error) {
+	listener := s.listener
+	if listener == nil {
+		var err error
+		if listener, err = net.Listen("tcp", s.srv.Addr); err != nil {
+			return nil, err
+		}
+	}
+	s.mu.Lock()
+	s.serving = listener
+	s.mu.Unlock()
+	if s.maxConnsPerIP > 0 {
+		listener = newPerIPListener(listener, s.maxConnsPerIP)
+	}
+	return listener, nil
 }
 
-func (s *service) Stop() {
+// Stop shuts the service down gracefully. The request contexts of streaming routes, those registered with
+// WithRouteTimeout(0) such as WebSocket routes, are canceled with ErrServiceStopped as soon as it begins, so that
+// streams end promptly. Other requests keep their contexts and are drained for up to the shutdown timeout, after
+// which the connections left open are closed and the remaining request contexts canceled.
+func (s *service) Stop() error {
 	// Stop the service
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.ctx == nil {
-		log.Fatal("Service already stopped")
+		return ErrServiceNotStarted
 	}
-	s.cancelFunc()
+	// Streams are told to finish so that the server can drain, while other in-flight requests keep their contexts
+	s.draining.Store(true)
+	close(s.streamShutdown)
 	s.ctx = nil
-	if err := s.srv.Close(); err != nil {
-		log.Fatal(err)
+	s.started.Store(false)
+	s.serving = nil
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	// Drain in-flight requests before running the hooks and closing anything left open
+	err := s.srv.Shutdown(ctx)
+	if h3Err := s.stopHTTP3(); err == nil {
+		err = h3Err
 	}
+	s.runShutdownHooks(ctx)
+	if closeErr := s.srv.Close(); err == nil {
+		err = closeErr
+	}
+	// Requests left running past the shutdown timeout, such as WebSocket connections, are canceled last
+	s.cancelFunc()
+	s.stopped.Store(true)
+	return err
 }
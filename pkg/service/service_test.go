@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestService returns a service built with the options, without starting it
+func newTestService(opts ...Option) *service {
+	return NewService(opts...).(*service)
+}
+
+// serve sends a request through the handler and returns the recorded response
+func serve(h http.Handler, method, target string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for name, value := range header {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// startTestService starts a service built with the options on an ephemeral loopback port, returning it along with
+// the channel receiving the result of Start. The service is stopped when the test ends.
+func startTestService(t *testing.T, opts ...Option) (*service, <-chan error) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestService(append(opts, WithListener(listener))...)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.Start()
+	}()
+	t.Cleanup(func() {
+		if err := s.Stop(); err != nil && !errors.Is(err, ErrServiceNotStarted) {
+			t.Errorf("stop: %v", err)
+		}
+	})
+	deadline := time.Now().Add(5 * time.Second)
+	for !s.isStarted() {
+		if time.Now().After(deadline) {
+			t.Fatal("service did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return s, errc
+}
+
+// isStarted reports whether Start has set up the service context
+func (s *service) isStarted() bool {
+	return s.started.Load()
+}
+
+// testURL returns the URL of the path on a service started with startTestService
+func (s *service) testURL(path string) string {
+	return "http://" + s.listener.Addr().String() + path
+}
+
+// writeTestCert writes a self-signed certificate for the hosts and 127.0.0.1, returning the paths of the certificate
+// and key files along with the certificate itself
+func writeTestCert(t *testing.T, hosts ...string) (string, string, tls.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		DNSNames:     hosts,
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, cert
+}
+
+func TestStartStop(t *testing.T) {
+	s, errc := startTestService(t)
+	if err := s.Start(); !errors.Is(err, ErrServiceStarted) {
+		t.Errorf("second start: got %v, want %v", err, ErrServiceStarted)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("start: got %v once stopped, want nil", err)
+	}
+	if err := s.Stop(); !errors.Is(err, ErrServiceNotStarted) {
+		t.Errorf("second stop: got %v, want %v", err, ErrServiceNotStarted)
+	}
+	if err := s.Start(); !errors.Is(err, ErrServiceStopped) {
+		t.Errorf("start after stop: got %v, want %v", err, ErrServiceStopped)
+	}
+	if err := s.Stop(); !errors.Is(err, ErrServiceNotStarted) {
+		t.Errorf("stop after restart attempt: got %v, want %v", err, ErrServiceNotStarted)
+	}
+}
+
+func TestMaxHeaderBytes(t *testing.T) {
+	s, _ := startTestService(t, WithMaxHeaderBytes(1024))
+	r, err := http.NewRequest(http.MethodGet, s.testURL("/health"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("X-Large", strings.Repeat("a", 8192))
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusRequestHeaderFieldsTooLarge)
+	}
+}
+
+func TestKeepAlivesDisabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		disabled bool
+	}{
+		{"enabled", false},
+		{"disabled", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := startTestService(t, WithKeepAlivesDisabled(tt.disabled))
+			resp, err := http.Get(s.testURL("/health"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.Close != tt.disabled {
+				t.Errorf("got connection close %v, want %v", resp.Close, tt.disabled)
+			}
+		})
+	}
+}
+
+func TestConnState(t *testing.T) {
+	var mu sync.Mutex
+	var states []http.ConnState
+	s, _ := startTestService(t, WithConnState(func(conn net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		states = append(states, state)
+	}))
+	client := &http.Client{Transport: &http.Transport{}}
+	resp, err := client.Get(s.testURL("/health"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	client.CloseIdleConnections()
+	want := []http.ConnState{http.StateNew, http.StateActive, http.StateIdle, http.StateClosed}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		got := append([]http.ConnState(nil), states...)
+		mu.Unlock()
+		if len(got) >= len(want) {
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("got states %v, want %v", got, want)
+				}
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got states %v, want %v", got, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestErrorLog(t *testing.T) {
+	logs := &lockedBuffer{}
+	built, err := Options{errorLog: slog.New(slog.NewTextHandler(logs, nil))}.buildServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.Config.ErrorLog = built.ErrorLog
+	srv.StartTLS()
+	defer srv.Close()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = conn.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+	_, _ = io.Copy(io.Discard, conn)
+	conn.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(logs.String(), "TLS handshake error") {
+		if time.Now().After(deadline) {
+			t.Fatalf("got logs %q, want the TLS handshake error", logs.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if out := logs.String(); !strings.Contains(out, "level=ERROR") {
+		t.Errorf("got logs %q, want them at error level", out)
+	}
+}
+
+func TestTLSNextProtos(t *testing.T) {
+	certFile, keyFile, _ := writeTestCert(t, "localhost")
+	tests := []struct {
+		name   string
+		protos []string
+		want   string
+	}{
+		{"default", nil, "h2"},
+		{"HTTP/1.1 only", []string{"http/1.1"}, "http/1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := startTestService(t, WithRequireTLS(true), WithCertFile(certFile), WithKeyFile(keyFile),
+				WithTLSNextProtos(tt.protos))
+			conn, err := tls.Dial("tcp", s.listener.Addr().String(),
+				&tls.Config{InsecureSkipVerify: true, NextProtos: []string{"h2", "http/1.1"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			if got := conn.ConnectionState().NegotiatedProtocol; got != tt.want {
+				t.Errorf("got protocol %q, want %q", got, tt.want)
+			}
+		})
+	}
+	invalid := Options{requireTLS: true, certFile: certFile, keyFile: certFile}
+	if _, err := invalid.buildServer(); err == nil {
+		t.Error("got no error for a mismatched key pair")
+	}
+}
+
+func TestTLSSessionTickets(t *testing.T) {
+	certFile, keyFile, _ := writeTestCert(t, "localhost")
+	tests := []struct {
+		name    string
+		tickets bool
+	}{
+		{"enabled", true},
+		{"disabled", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := startTestService(t, WithRequireTLS(true), WithCertFile(certFile), WithKeyFile(keyFile),
+				WithTLSSessionTickets(tt.tickets))
+			client := &http.Client{Transport: &http.Transport{
+				DisableKeepAlives: true,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true,
+					ClientSessionCache: tls.NewLRUClientSessionCache(1)},
+			}}
+			url := "https://" + s.listener.Addr().String() + "/health"
+			var resumed bool
+			for i := 0; i < 2; i++ {
+				resp, err := client.Get(url)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				resumed = resp.TLS.DidResume
+			}
+			if resumed != tt.tickets {
+				t.Errorf("got resumed %v on the second connection, want %v", resumed, tt.tickets)
+			}
+		})
+	}
+}
+
+func TestTLSClientSessionCacheSize(t *testing.T) {
+	certFile, keyFile, _ := writeTestCert(t, "localhost")
+	s, _ := startTestService(t, WithRequireTLS(true), WithCertFile(certFile), WithKeyFile(keyFile),
+		WithTLSClientSessionCacheSize(4))
+	if s.clientSessions == nil {
+		t.Fatal("got no client session cache, want one of size 4")
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Ping(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if newTestService().clientSessions != nil {
+		t.Error("got a client session cache without a size set")
+	}
+}
+
+func TestReadHeaderTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want time.Duration
+	}{
+		{"default", nil, DefaultReadHeaderTimeout},
+		{"shorter request timeout", []Option{WithRequestTimeout(2 * time.Second)}, 2 * time.Second},
+		{"no request timeout", []Option{WithRequestTimeout(0)}, DefaultReadHeaderTimeout},
+		{"set", []Option{WithRequestTimeout(2 * time.Second), WithReadHeaderTimeout(5 * time.Second)}, 5 * time.Second},
+		{"disabled", []Option{WithReadHeaderTimeout(0)}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newTestService(tt.opts...).srv.ReadHeaderTimeout; got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+	t.Run("slow headers", func(t *testing.T) {
+		s, _ := startTestService(t, WithReadHeaderTimeout(100*time.Millisecond))
+		conn, err := net.Dial("tcp", s.listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		start := time.Now()
+		if _, err := conn.Write([]byte("GET /health HTTP/1.1\r\nHost: localhost\r\n")); err != nil {
+			t.Fatal(err)
+		}
+		// The headers are never finished, so the server must close the connection at the header timeout
+		_, err = io.Copy(io.Discard, conn)
+		if elapsed := time.Since(start); err != nil || elapsed > 2*time.Second {
+			t.Errorf("got connection closed after %v with error %v, want it closed at the header timeout", elapsed, err)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+// Package servicetest runs a service on an ephemeral loopback port for integration tests
+package servicetest
+
+import (
+	"context"
+	"errors"
+	"github.com/bchisham/http-simple/pkg/service"
+	"net"
+	"sync"
+	"time"
+)
+
+// startTimeout bounds how long NewTestServer waits for the service to start
+const startTimeout = 5 * time.Second
+
+// TestServer is a service serving on an ephemeral loopback port
+type TestServer struct {
+	service.Service
+	// URL is the base URL of the server, such as http://127.0.0.1:50321
+	URL       string
+	listener  net.Listener
+	errc      chan error
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewTestServer starts a service configured with the options on an ephemeral loopback port, returning once the
+// service has started so the server can be requested straight away. The server serves plain HTTP.
+func NewTestServer(opts ...service.Option) (*TestServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	svc := service.NewService(append(opts, service.WithListener(listener))...)
+	ts := &TestServer{
+		Service:  svc,
+		URL:      "http://" + listener.Addr().String(),
+		listener: listener,
+		errc:     make(chan error, 1),
+	}
+	go func() {
+		ts.errc <- svc.Start()
+	}()
+	if err := ts.waitStarted(); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return ts, nil
+}
+
+// waitStarted waits until the service has started, returning the error if it fails to start
+func (ts *TestServer) waitStarted() error {
+	deadline := time.Now().Add(startTimeout)
+	for {
+		// Ping reports ErrServiceNotStarted until Start has set up the service; any other result means it has started
+		if err := ts.Ping(context.Background()); !errors.Is(err, service.ErrServiceNotStarted) {
+			return nil
+		}
+		select {
+		case err := <-ts.errc:
+			if err == nil {
+				err = errors.New("service stopped before it started")
+			}
+			return err
+		case <-time.After(time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			return errors.New("service did not start")
+		}
+	}
+}
+
+// Close stops the server, returning the first error from stopping or serving. Calling Close again returns the same
+// result.
+func (ts *TestServer) Close() error {
+	ts.closeOnce.Do(func() {
+		err := ts.Stop()
+		if errors.Is(err, service.ErrServiceNotStarted) {
+			// The service was stopped through the embedded Service, so Start has already returned
+			err = nil
+		}
+		if serveErr := <-ts.errc; err == nil {
+			err = serveErr
+		}
+		ts.closeErr = err
+	})
+	return ts.closeErr
+}
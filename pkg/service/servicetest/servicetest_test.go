@@ -0,0 +1,62 @@
+package servicetest
+
+import (
+	"context"
+	"github.com/bchisham/http-simple/pkg/service"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestNewTestServer(t *testing.T) {
+	ts, err := NewTestServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(ts.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || string(body) != "OK" {
+		t.Errorf("got status %d with body %q, want 200 with OK", resp.StatusCode, body)
+	}
+	if err := ts.Ping(context.Background()); err != nil {
+		t.Errorf("ping: %v", err)
+	}
+	if err := ts.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := ts.Close(); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+}
+
+func TestCloseImmediately(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		ts, err := NewTestServer(service.WithDisableHealthHandler(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ts.Close(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+	}
+}
+
+func TestCloseAfterStop(t *testing.T) {
+	ts, err := NewTestServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.Stop(); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if err := ts.Close(); err != nil {
+		t.Errorf("close: %v", err)
+	}
+}
@@ -24,6 +24,8 @@ func (s *Session) Start(request Request) (*Session, error) {
 	return s, nil
 }
 
+// Save saves the session, setting its cookie through the writer the route handler was given so that it passes the
+// handler timeout and route middleware
 func (s *Session) Save(request Request) error {
 	err := s.Session.Save(request.httpRequest, request.writer)
 	if err != nil {
@@ -31,3 +33,18 @@ func (s *Session) Save(request Request) error {
 	}
 	return nil
 }
+
+// WithSessionStore sets the store used by Request.Session
+func WithSessionStore(store sessions.Store) Option {
+	return func(o *Options) {
+		o.sessionStore = store
+	}
+}
+
+// Session returns the request session from the store configured with WithSessionStore
+func (r *Request) Session() (*sessions.Session, error) {
+	if r.options == nil || r.options.sessionStore == nil {
+		return nil, ErrNoSessionStore
+	}
+	return r.options.sessionStore.Get(r.httpRequest, r.sessionName)
+}
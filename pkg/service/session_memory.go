@@ -0,0 +1,154 @@
+package service
+
+import (
+	"encoding/base32"
+	"github.com/bchisham/http-simple/pkg/service/internal/clock"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var sessionIDEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MemoryStore is a sessions.Store keeping session values in memory, with only the session ID stored in the cookie.
+// Sessions expire after the store TTL and are evicted by a background goroutine until the store is closed.
+type MemoryStore struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+	ttl     time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	done     chan struct{}
+	close    sync.Once
+}
+
+type memorySession struct {
+	values  map[interface{}]interface{}
+	expires time.Time
+}
+
+// NewMemoryStore returns a MemoryStore whose sessions expire after the TTL. When key pairs are given the session ID
+// cookie is signed and optionally encrypted, as for sessions.NewCookieStore.
+func NewMemoryStore(ttl time.Duration, keyPairs ...[]byte) *MemoryStore {
+	s := &MemoryStore{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:   "/",
+			MaxAge: int(ttl / time.Second),
+		},
+		ttl:      ttl,
+		sessions: make(map[string]memorySession),
+		done:     make(chan struct{}),
+	}
+	// The clock is read here rather than by the goroutine, so that eviction keeps the clock the store was created with
+	go s.evict(clk)
+	return s
+}
+
+// Get returns the named session after adding it to the request registry
+func (s *MemoryStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns the named session, loading its values when the request carries a live session ID
+func (s *MemoryStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var id string
+	if err := s.decodeID(name, c.Value, &id); err != nil {
+		return session, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.sessions[id]
+	if !ok || clk.Now().After(stored.expires) {
+		return session, nil
+	}
+	session.ID = id
+	session.Values = copyValues(stored.values)
+	session.IsNew = false
+	return session, nil
+}
+
+// Save stores the session values and writes the session ID cookie. A session with Options.MaxAge < 0 is deleted.
+func (s *MemoryStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		s.mu.Lock()
+		delete(s.sessions, session.ID)
+		s.mu.Unlock()
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+	if session.ID == "" {
+		session.ID = sessionIDEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+	}
+	encoded, err := s.encodeID(session.Name(), session.ID)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.sessions[session.ID] = memorySession{values: copyValues(session.Values), expires: clk.Now().Add(s.ttl)}
+	s.mu.Unlock()
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// Close stops the background eviction
+func (s *MemoryStore) Close() {
+	s.close.Do(func() {
+		close(s.done)
+	})
+}
+
+func (s *MemoryStore) evict(clk clock.Clock) {
+	interval := s.ttl / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-clk.After(interval):
+			s.mu.Lock()
+			for id, stored := range s.sessions {
+				if now.After(stored.expires) {
+					delete(s.sessions, id)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *MemoryStore) encodeID(name, id string) (string, error) {
+	if len(s.Codecs) == 0 {
+		return id, nil
+	}
+	return securecookie.EncodeMulti(name, id, s.Codecs...)
+}
+
+func (s *MemoryStore) decodeID(name, value string, id *string) error {
+	if len(s.Codecs) == 0 {
+		*id = value
+		return nil
+	}
+	return securecookie.DecodeMulti(name, value, id, s.Codecs...)
+}
+
+func copyValues(values map[interface{}]interface{}) map[interface{}]interface{} {
+	copied := make(map[interface{}]interface{}, len(values))
+	for k, v := range values {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	fake := useFakeClock(t)
+	store := NewMemoryStore(50*time.Millisecond, []byte("0123456789abcdef0123456789abcdef"))
+	defer store.Close()
+
+	session, err := store.New(httptest.NewRequest(http.MethodGet, "/", nil), SessionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Values["user"] = "alice"
+	w := httptest.NewRecorder()
+	if err := session.Save(httptest.NewRequest(http.MethodGet, "/", nil), w); err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	load := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cookies[0])
+		return r
+	}
+
+	loaded, err := store.New(load(), SessionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.IsNew || loaded.Values["user"] != "alice" {
+		t.Errorf("got new %v with user %v, want the saved session", loaded.IsNew, loaded.Values["user"])
+	}
+
+	fake.Advance(60 * time.Millisecond)
+	expired, _ := store.New(load(), SessionName)
+	if !expired.IsNew || expired.Values["user"] != nil {
+		t.Errorf("got new %v with user %v, want an expired session", expired.IsNew, expired.Values["user"])
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"github.com/gorilla/sessions"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSessionSave(t *testing.T) {
+	store := NewMemoryStore(time.Minute, []byte("0123456789abcdef0123456789abcdef"))
+	defer store.Close()
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		code    int
+		cookie  bool
+	}{
+		{"saved", time.Second, http.StatusNoContent, true},
+		// Once the timeout response has been sent, the cookie must not reach it
+		{"timed out", 10 * time.Millisecond, http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release, saved := make(chan struct{}), make(chan struct{})
+			s := newTestService(WithSessionStore(store), WithHandlerTimeout(tt.timeout))
+			s.POST("/login", func(w http.ResponseWriter, r *http.Request) {
+				defer close(saved)
+				request, _ := RequestFromContext(r.Context())
+				if tt.cookie {
+					close(release)
+				}
+				<-release
+				session, err := NewSession("", sessions.Options{}, store).Start(*request)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				session.Session.Values["user"] = "alice"
+				_ = session.Save(*request)
+				w.WriteHeader(http.StatusNoContent)
+			})
+			w := post(s.mux, "/login", "", nil)
+			if !tt.cookie {
+				close(release)
+			}
+			<-saved
+			if w.Code != tt.code {
+				t.Fatalf("got status %d, want %d", w.Code, tt.code)
+			}
+			if got := w.Header().Get("Set-Cookie") != ""; got != tt.cookie {
+				t.Errorf("got session cookie %v, want %v", got, tt.cookie)
+			}
+		})
+	}
+}
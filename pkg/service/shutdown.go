@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// notifySignals relays signals to the channel. It is a variable so that signal delivery can be simulated.
+var notifySignals = signal.Notify
+
+// ShutdownHook is called when the service stops, after connections have drained
+type ShutdownHook func(ctx context.Context) error
+
+// WithShutdownHook appends a hook run by Stop after in-flight requests have drained and before the server is closed.
+// Hooks run in the order registered, sharing the shutdown timeout.
+func WithShutdownHook(hook ShutdownHook) Option {
+	return func(o *Options) {
+		o.shutdownHooks = append(o.shutdownHooks, hook)
+	}
+}
+
+// WithShutdownTimeout bounds the time Stop waits for in-flight requests and shutdown hooks
+func WithShutdownTimeout(shutdownTimeout time.Duration) Option {
+	return func(o *Options) {
+		o.shutdownTimeout = shutdownTimeout
+	}
+}
+
+// WithGracePeriodOnSIGTERM keeps serving for the grace period after StartWithSignals receives a shutdown signal,
+// failing the /readyz readiness probe meanwhile so that load balancers stop routing new traffic before the server
+// drains. A second signal ends the grace period early.
+func WithGracePeriodOnSIGTERM(gracePeriod time.Duration) Option {
+	return func(o *Options) {
+		o.gracePeriod = gracePeriod
+	}
+}
+
+// WithShutdownContext stops the service gracefully, as Stop does, once the context is done, so that an application
+// lifecycle manager can shut it down without holding on to the service. Start returns once the service has stopped.
+func WithShutdownContext(ctx context.Context) Option {
+	return func(o *Options) {
+		o.shutdownContext = ctx
+	}
+}
+
+// stopOnShutdownContext arranges for the service to stop when the shutdown context is done, returning a function
+// releasing the arrangement, which waits for Stop to finish if the context has already triggered it
+func (s *service) stopOnShutdownContext() func() {
+	if s.shutdownContext == nil {
+		return func() {}
+	}
+	stopped := make(chan struct{})
+	stop := context.AfterFunc(s.shutdownContext, func() {
+		defer close(stopped)
+		if err := s.Stop(); err != nil && !errors.Is(err, ErrServiceNotStarted) {
+			slog.Error("error stopping service on shutdown context", "error", err)
+		}
+	})
+	return func() {
+		if !stop() {
+			<-stopped
+		}
+	}
+}
+
+// runShutdownHooks runs every hook, logging rather than returning errors so that one failing hook does not prevent
+// the others from running
+func (s *service) runShutdownHooks(ctx context.Context) {
+	for _, hook := range s.shutdownHooks {
+		if err := hook(ctx); err != nil {
+			slog.ErrorContext(ctx, "error running shutdown hook", "error", err)
+		}
+	}
+}
+
+// StartWithSignals starts the service and stops it gracefully when one of the signals is received, by default
+// SIGINT or SIGTERM. It blocks until the service has stopped.
+func (s *service) StartWithSignals(signals ...os.Signal) error {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	received := make(chan os.Signal, 1)
+	notifySignals(received, signals...)
+	defer signal.Stop(received)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.Start()
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case sig := <-received:
+		s.draining.Store(true)
+		if s.gracePeriod > 0 {
+			slog.Info("received signal, stopping service after grace period", "signal", sig.String(),
+				"grace_period", s.gracePeriod)
+			timer := time.NewTimer(s.gracePeriod)
+			select {
+			case <-timer.C:
+			case <-received:
+				timer.Stop()
+			case err := <-errc:
+				timer.Stop()
+				return err
+			}
+		}
+		slog.Info("received signal, stopping service", "signal", sig.String())
+		if err := s.Stop(); err != nil {
+			return err
+		}
+		return <-errc
+	}
+}
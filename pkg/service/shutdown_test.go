@@ -0,0 +1,267 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestShutdownHooks(t *testing.T) {
+	var order []string
+	s, _ := startTestService(t,
+		WithShutdownHook(func(ctx context.Context) error {
+			order = append(order, "first")
+			return errors.New("hook failed")
+		}),
+		WithShutdownHook(func(ctx context.Context) error {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("hook context has no deadline, want the shutdown timeout")
+			}
+			order = append(order, "second")
+			return nil
+		}),
+	)
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"first", "second"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("got hooks run %v, want %v", order, want)
+	}
+}
+
+func TestStartWithSignals(t *testing.T) {
+	relays := make(chan chan<- os.Signal, 1)
+	notifySignals = func(c chan<- os.Signal, sig ...os.Signal) {
+		relays <- c
+	}
+	defer func() {
+		notifySignals = signal.Notify
+	}()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestService(WithListener(listener))
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.StartWithSignals()
+	}()
+	relay := <-relays
+	for !s.isStarted() {
+		time.Sleep(time.Millisecond)
+	}
+	relay <- syscall.SIGTERM
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("got %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("service did not stop on SIGTERM")
+	}
+	if state := s.lifecycleState(); state != LifecycleStopped {
+		t.Errorf("got state %s, want %s", state, LifecycleStopped)
+	}
+}
+
+func TestShutdownContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, errc := startTestService(t, WithShutdownContext(ctx))
+	cancel()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("got %v from Start, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("service did not stop when the shutdown context was canceled")
+	}
+	if state := s.lifecycleState(); state != LifecycleStopped {
+		t.Errorf("got state %s, want %s", state, LifecycleStopped)
+	}
+}
+
+func TestStopEndsStreams(t *testing.T) {
+	streaming := make(chan struct{})
+	s, _ := startTestService(t, WithShutdownTimeout(5*time.Second))
+	s.GET("/stream", func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		ctx, cancel := context.WithCancelCause(request.Context())
+		defer cancel(nil)
+		ch := make(chan []byte)
+		go func() {
+			for {
+				select {
+				case ch <- bytes.Repeat([]byte("t"), 1024):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		close(streaming)
+		_, _ = BinaryStreamDataWithCancel(ctx, *request, ch, cancel)()
+	}, WithRouteTimeout(0))
+	resp, err := http.Get(s.testURL("/stream"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, resp.Body)
+		done <- err
+	}()
+	<-streaming
+	start := time.Now()
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("stop took %v, want the stream to end without waiting for the shutdown timeout", elapsed)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("got %v reading the stream, want it to end cleanly", err)
+	}
+}
+
+func TestStopCancelsStreamingRequests(t *testing.T) {
+	streaming := make(chan struct{})
+	causes := make(chan error, 1)
+	s, _ := startTestService(t, WithShutdownTimeout(5*time.Second))
+	s.GET("/events", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("data: hello\n\n"))
+		w.(http.Flusher).Flush()
+		close(streaming)
+		<-r.Context().Done()
+		causes <- context.Cause(r.Context())
+	}, WithRouteTimeout(0))
+	resp, err := http.Get(s.testURL("/events"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	<-streaming
+	start := time.Now()
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("stop took %v, want the request context canceled as draining began", elapsed)
+	}
+	if cause := <-causes; !errors.Is(cause, ErrServiceStopped) {
+		t.Errorf("got cause %v, want %v", cause, ErrServiceStopped)
+	}
+}
+
+func TestStopDrainsRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	s, _ := startTestService(t, WithShutdownTimeout(5*time.Second))
+	s.GET("/slow", Handler(func(r *Request) *Response {
+		close(started)
+		<-release
+		// The request context must still be live while the server drains, so that the response is sent
+		if err := r.Context().Err(); err != nil {
+			return r.ResponseBuilder().WithStatus(http.StatusInternalServerError).Build()
+		}
+		return r.ResponseBuilder().WithStatus(http.StatusOK).WithBody([]byte("done")).Build()
+	}).ServeHTTP)
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(s.testURL("/slow"))
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		results <- result{resp.StatusCode, string(body), err}
+	}()
+	<-started
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- s.Stop()
+	}()
+	for !s.draining.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	got := <-results
+	if got.err != nil || got.status != http.StatusOK || got.body != "done" {
+		t.Errorf("got status %d, body %q, error %v, want 200 done", got.status, got.body, got.err)
+	}
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGracePeriodOnSIGTERM(t *testing.T) {
+	relays := make(chan chan<- os.Signal, 1)
+	notifySignals = func(c chan<- os.Signal, sig ...os.Signal) {
+		relays <- c
+	}
+	defer func() {
+		notifySignals = signal.Notify
+	}()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestService(WithListener(listener), WithGracePeriodOnSIGTERM(time.Second))
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.StartWithSignals()
+	}()
+	relay := <-relays
+	for !s.isStarted() {
+		time.Sleep(time.Millisecond)
+	}
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	status := func(path string) int {
+		resp, err := client.Get(s.testURL(path))
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if ready, live := status("/readyz"), status("/livez"); ready != http.StatusOK || live != http.StatusOK {
+		t.Fatalf("before the signal: got readyz %d and livez %d, want 200", ready, live)
+	}
+	relay <- syscall.SIGTERM
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for status("/readyz") != http.StatusServiceUnavailable {
+		if time.Now().After(deadline) {
+			t.Fatal("readyz did not fail during the grace period")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if live := status("/livez"); live != http.StatusOK {
+		t.Errorf("during the grace period: got livez %d, want 200", live)
+	}
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("got %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("service did not stop after the grace period")
+	}
+	if live := status("/livez"); live != 0 {
+		t.Errorf("after the grace period: got livez %d, want the connection refused", live)
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// VerifyHMAC verifies that the named header carries a hex encoded HMAC-SHA256 of the request body, optionally
+// prefixed with "sha256=". The body remains readable by the handler.
+func (r *Request) VerifyHMAC(secret []byte, signatureHeader string) error {
+	signature := strings.TrimPrefix(r.httpRequest.Header.Get(signatureHeader), "sha256=")
+	if signature == "" {
+		return fmt.Errorf("%w: missing %s header", ErrInvalidSignature, signatureHeader)
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: malformed %s header", ErrInvalidSignature, signatureHeader)
+	}
+	body, err := r.Body()
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerifyHMAC(t *testing.T) {
+	secret := []byte("secret")
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("payload"))
+	signature := hex.EncodeToString(mac.Sum(nil))
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		wantErr   bool
+	}{
+		{"valid", "payload", signature, false},
+		{"valid with prefix", "payload", "sha256=" + signature, false},
+		{"tampered body", "payload!", signature, true},
+		{"missing", "payload", "", true},
+		{"malformed", "payload", "not-hex", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hr := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(tt.body))
+			if tt.signature != "" {
+				hr.Header.Set("X-Signature", tt.signature)
+			}
+			r := NewRequest(hr.Context(), hr, httptest.NewRecorder())
+			err := r.VerifyHMAC(secret, "X-Signature")
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSignature) || StatusFromError(err) != http.StatusUnauthorized {
+					t.Fatalf("got error %v, want ErrInvalidSignature", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			// The handler can still read the body
+			body, _ := io.ReadAll(hr.Body)
+			if string(body) != tt.body {
+				t.Errorf("got body %q after verification, want %q", body, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TrailingSlashMode selects how requests differing from a registered route by a trailing slash are routed
+type TrailingSlashMode int
+
+const (
+	// TrailingSlashStrict routes a request only to routes registered with exactly the same path
+	TrailingSlashStrict TrailingSlashMode = iota
+	// TrailingSlashRedirect redirects a request for an unregistered path to the registered form with or without the
+	// trailing slash, using 301 for GET and HEAD requests and 308 otherwise so that the method and body are kept
+	TrailingSlashRedirect
+	// TrailingSlashStrip removes the trailing slash from request paths before routing, so handlers see /users for
+	// a request to /users/
+	TrailingSlashStrip
+	// TrailingSlashIgnore routes a request for an unregistered path to the route registered with or without the
+	// trailing slash, leaving the request path as it was sent
+	TrailingSlashIgnore
+)
+
+// WithTrailingSlashRedirect sets how requests differing from a registered route by a trailing slash are routed
+func WithTrailingSlashRedirect(mode TrailingSlashMode) Option {
+	return func(o *Options) {
+		o.trailingSlash = mode
+	}
+}
+
+// alternatePath returns the request path with the trailing slash added or removed when a route is registered for it
+func (s *service) alternatePath(r *http.Request) (string, bool) {
+	if s.trailingSlash != TrailingSlashRedirect && s.trailingSlash != TrailingSlashIgnore || r.URL.Path == "/" {
+		return "", false
+	}
+	// A path starting with // would redirect to another host
+	if strings.HasPrefix(r.URL.Path, "//") {
+		return "", false
+	}
+	alternate := r.URL.Path + "/"
+	if strings.HasSuffix(r.URL.Path, "/") {
+		alternate = strings.TrimSuffix(r.URL.Path, "/")
+	}
+	matched, _, allowed := s.routes.match(r.Method, alternate)
+	return alternate, matched != nil || len(allowed) > 0
+}
+
+func stripTrailingSlash(u *url.URL) {
+	if len(u.Path) > 1 && strings.HasSuffix(u.Path, "/") {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = ""
+	}
+}
+
+// redirectToPath redirects the request to the same URL with the path replaced
+func redirectToPath(w http.ResponseWriter, r *http.Request, path string) {
+	u := *r.URL
+	u.Path = path
+	u.RawPath = ""
+	code := http.StatusMovedPermanently
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+	http.Redirect(w, r, u.RequestURI(), code)
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTrailingSlash(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     TrailingSlashMode
+		path     string
+		code     int
+		body     string
+		location string
+	}{
+		{"strict", TrailingSlashStrict, "/users/", http.StatusNotFound, "", ""},
+		{"redirect to without", TrailingSlashRedirect, "/users/?a=1", http.StatusMovedPermanently, "", "/users?a=1"},
+		{"redirect to with", TrailingSlashRedirect, "/dirs", http.StatusMovedPermanently, "", "/dirs/"},
+		{"strip", TrailingSlashStrip, "/users/", http.StatusOK, "users /users", ""},
+		{"ignore without", TrailingSlashIgnore, "/users/", http.StatusOK, "users /users/", ""},
+		{"ignore with", TrailingSlashIgnore, "/dirs", http.StatusOK, "dirs /dirs", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(WithTrailingSlashRedirect(tt.mode))
+			s.GET("/users", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("users " + r.URL.Path))
+			})
+			s.GET("/dirs/", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("dirs " + r.URL.Path))
+			})
+			w := serve(s.mux, http.MethodGet, tt.path, nil)
+			if w.Code != tt.code {
+				t.Fatalf("got status %d, want %d", w.Code, tt.code)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("got %q, want %q", w.Body.String(), tt.body)
+			}
+			if location := w.Header().Get("Location"); location != tt.location {
+				t.Errorf("got Location %q, want %q", location, tt.location)
+			}
+		})
+	}
+}
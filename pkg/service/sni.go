@@ -0,0 +1,36 @@
+package service
+
+import (
+	"crypto/tls"
+	"strings"
+)
+
+// WithCertForHost serves the certificate to TLS clients asking for the host through SNI. The host may be a wildcard
+// such as *.example.com matching a single label. Clients asking for any other host, or none, are served the
+// certificate loaded from the certificate and key files.
+func WithCertForHost(host string, cert tls.Certificate) Option {
+	return func(o *Options) {
+		if o.hostCertificates == nil {
+			o.hostCertificates = make(map[string]*tls.Certificate)
+		}
+		o.hostCertificates[strings.ToLower(host)] = &cert
+	}
+}
+
+// certificateForHost selects the certificate for the server name sent by the client, returning nil to fall back to
+// the default certificate
+func (o Options) certificateForHost(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if name == "" {
+		return nil, nil
+	}
+	if cert, ok := o.hostCertificates[name]; ok {
+		return cert, nil
+	}
+	if _, parent, ok := strings.Cut(name, "."); ok {
+		if cert, ok := o.hostCertificates["*."+parent]; ok {
+			return cert, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestCertForHost(t *testing.T) {
+	certFile, keyFile, _ := writeTestCert(t, "default.test")
+	_, _, exact := writeTestCert(t, "a.test")
+	_, _, wildcard := writeTestCert(t, "*.b.test")
+	s, _ := startTestService(t, WithRequireTLS(true), WithCertFile(certFile), WithKeyFile(keyFile),
+		WithCertForHost("A.test", exact), WithCertForHost("*.b.test", wildcard))
+	tests := []struct {
+		serverName string
+		want       string
+	}{
+		{"a.test", "a.test"},
+		{"x.b.test", "*.b.test"},
+		{"other.test", "default.test"},
+		{"", "default.test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.serverName, func(t *testing.T) {
+			conn, err := tls.Dial("tcp", s.listener.Addr().String(),
+				&tls.Config{InsecureSkipVerify: true, ServerName: tt.serverName})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			if got := conn.ConnectionState().PeerCertificates[0].Subject.CommonName; got != tt.want {
+				t.Errorf("got certificate for %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import "net/http"
+
+// WithDisableContentSniffing stops responses from being sniffed for their content type, by the server and by
+// browsers. Responses carry X-Content-Type-Options: nosniff, and a response written without a Content-Type is sent
+// without one rather than with a type guessed from its first bytes. Streams from BinaryStreamData are sent as
+// application/octet-stream unless the handler sets a type.
+func WithDisableContentSniffing(disableContentSniffing bool) Option {
+	return func(o *Options) {
+		o.disableContentSniffing = disableContentSniffing
+	}
+}
+
+// contentSniffingDisabled reports whether the request is served with content sniffing disabled
+func (r *Request) contentSniffingDisabled() bool {
+	return r.options != nil && r.options.disableContentSniffing
+}
+
+func noSniffMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		next.ServeHTTP(&noSniffWriter{ResponseWriter: w}, r)
+	})
+}
+
+// noSniffWriter keeps the server from detecting the content type of a response sent without one. The type is only
+// suppressed as the response is committed, so that handlers such as http.FileServer still see it unset beforehand.
+type noSniffWriter struct {
+	http.ResponseWriter
+}
+
+func (w *noSniffWriter) WriteHeader(status int) {
+	if status >= 200 || status == http.StatusSwitchingProtocols {
+		w.suppressContentType()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *noSniffWriter) Write(b []byte) (int, error) {
+	w.suppressContentType()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *noSniffWriter) Flush() {
+	w.suppressContentType()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer
+func (w *noSniffWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// suppressContentType marks the content type as present but empty, which the server sends as no Content-Type
+func (w *noSniffWriter) suppressContentType() {
+	if _, ok := w.Header()["Content-Type"]; !ok {
+		w.Header()["Content-Type"] = nil
+	}
+}
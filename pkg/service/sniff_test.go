@@ -0,0 +1,50 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDisableContentSniffing(t *testing.T) {
+	tests := []struct {
+		name        string
+		disabled    bool
+		contentType string
+		noSniff     string
+	}{
+		{"sniffed", false, "text/html; charset=utf-8", ""},
+		{"disabled", true, "", "nosniff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(WithDisableContentSniffing(tt.disabled))
+			s.GET("/ambiguous", writeBody("<html><body>user content</body></html>"))
+			s.GET("/typed", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = w.Write([]byte("<html></html>"))
+			})
+			srv := httptest.NewServer(s)
+			defer srv.Close()
+			resp, err := http.Get(srv.URL + "/ambiguous")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got := resp.Header.Get("Content-Type"); got != tt.contentType {
+				t.Errorf("got Content-Type %q, want %q", got, tt.contentType)
+			}
+			if got := resp.Header.Get("X-Content-Type-Options"); got != tt.noSniff {
+				t.Errorf("got X-Content-Type-Options %q, want %q", got, tt.noSniff)
+			}
+			resp, err = http.Get(srv.URL + "/typed")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got := resp.Header.Get("Content-Type"); got != "text/plain" {
+				t.Errorf("got Content-Type %q for a typed response, want text/plain", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The timeout applied to a handler is resolved in order of precedence:
+//
+//  1. the route timeout set with WithRouteTimeout
+//  2. the service handler timeout set with WithHandlerTimeout
+//  3. the connection write timeout set with WithRequestTimeout
+//
+// The first two bound the handler with http.TimeoutHandler, which responds with 503 and cancels the request context
+// when the timeout elapses. http.TimeoutHandler buffers the whole response until the handler returns and its writer
+// does not implement http.Flusher, so routes that flush, such as streams and server-sent events, must be registered
+// with WithRouteTimeout(0) whenever a handler timeout is set. The connection write timeout always cancels the request
+// context once the write deadline passes, as the connection can no longer be written to. A request context timeout
+// set with WithRequestContextTimeout only cancels the request context, leaving the response to the handler. A zero
+// route timeout marks a streaming route and disables all of them, including the write deadline on the connection.
+//
+// The stream helpers BinaryStreamData and StreamJSONArray push the connection write deadline, and with it the request
+// context deadline, a full write timeout ahead before each chunk, so that a stream outlasting the write timeout is
+// only cut off once the client stops keeping up. When the writer cannot have its deadline extended, a warning is
+// logged instead.
+
+// WithRequestContextTimeout sets a deadline on the context of each request, measured from its arrival, so that
+// outbound calls made with it are canceled once the budget is spent. Unlike WithHandlerTimeout it does not write a
+// timeout response.
+func WithRequestContextTimeout(requestContextTimeout time.Duration) Option {
+	return func(o *Options) {
+		o.requestContextTimeout = requestContextTimeout
+	}
+}
+
+// effectiveTimeout returns the timeout applied to the request handler and whether the route is streaming
+func (s *service) effectiveTimeout(request *Request) (time.Duration, bool) {
+	if request.route != nil && request.route.timeoutSet {
+		return request.route.timeout, request.route.timeout == 0
+	}
+	return s.handlerTimeout, false
+}
+
+// withTimeout wraps the handler according to the timeout precedence for the request
+func (s *service) withTimeout(w http.ResponseWriter, request *Request, handler http.Handler) http.Handler {
+	timeout, streaming := s.effectiveTimeout(request)
+	switch {
+	case streaming:
+		err := http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			request.Logger().WarnContext(request.Context(), "error clearing write deadline", "error", err)
+		}
+		return withStreamShutdown(request, handler)
+	case timeout > 0:
+		handler = http.TimeoutHandler(handler, timeout, "Service Unavailable")
+	}
+	if s.requestTimeout > 0 {
+		handler = withWriteDeadline(request, request.start.Add(s.requestTimeout), handler)
+	}
+	if s.requestContextTimeout > 0 {
+		handler = withDeadline(request, request.start.Add(s.requestContextTimeout), handler)
+	}
+	return handler
+}
+
+// withStreamShutdown cancels the request context of a streaming route with ErrServiceStopped as soon as the service
+// begins shutting down, so that streams end and let the server drain
+func withStreamShutdown(request *Request, handler http.Handler) http.Handler {
+	if request.shutdown == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancelCause(r.Context())
+		defer cancel(nil)
+		go func() {
+			select {
+			case <-request.shutdown:
+				cancel(ErrServiceStopped)
+			case <-ctx.Done():
+			}
+		}()
+		request.setContext(ctx)
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// withDeadline cancels the request context at the deadline so that handlers stop work that can no longer be sent
+func withDeadline(request *Request, deadline time.Time, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithDeadline(r.Context(), deadline)
+		defer cancel()
+		request.setContext(ctx)
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// withWriteDeadline cancels the request context at the connection write deadline, which stream helpers may extend
+func withWriteDeadline(request *Request, deadline time.Time, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := newWriteDeadlineContext(r.Context(), deadline)
+		defer ctx.stop()
+		request.writeDeadline = ctx
+		request.setContext(ctx)
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// writeDeadlineContext is a context canceled with context.DeadlineExceeded at a deadline that can be extended
+type writeDeadlineContext struct {
+	context.Context
+	cancel   context.CancelCauseFunc
+	mu       sync.Mutex
+	deadline time.Time
+	timer    *time.Timer
+	expired  bool
+	warned   bool
+}
+
+func newWriteDeadlineContext(parent context.Context, deadline time.Time) *writeDeadlineContext {
+	ctx, cancel := context.WithCancelCause(parent)
+	d := &writeDeadlineContext{Context: ctx, cancel: cancel, deadline: deadline}
+	d.timer = time.AfterFunc(time.Until(deadline), d.expire)
+	return d
+}
+
+func (d *writeDeadlineContext) Deadline() (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if parent, ok := d.Context.Deadline(); ok && parent.Before(d.deadline) {
+		return parent, true
+	}
+	return d.deadline, true
+}
+
+func (d *writeDeadlineContext) Err() error {
+	err := d.Context.Err()
+	if err == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.expired {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// expire cancels the context unless the deadline was extended while the timer fired
+func (d *writeDeadlineContext) expire() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.expired || time.Now().Before(d.deadline) {
+		return
+	}
+	d.expired = true
+	d.cancel(context.DeadlineExceeded)
+}
+
+// extend moves the deadline, reporting false once it has already passed
+func (d *writeDeadlineContext) extend(deadline time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.expired {
+		return false
+	}
+	d.deadline = deadline
+	d.timer.Reset(time.Until(deadline))
+	return true
+}
+
+// stop releases the timer once the handler has returned
+func (d *writeDeadlineContext) stop() {
+	d.timer.Stop()
+	d.cancel(context.Canceled)
+}
+
+// extendWriteDeadline pushes the connection write deadline and the request context deadline a write timeout ahead.
+// It is called by stream helpers before each chunk.
+func (r *Request) extendWriteDeadline(w http.ResponseWriter) {
+	d := r.writeDeadline
+	if d == nil || r.options == nil || r.options.requestTimeout <= 0 {
+		return
+	}
+	deadline := time.Now().Add(r.options.requestTimeout)
+	if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil {
+		d.mu.Lock()
+		warned := d.warned
+		d.warned = true
+		d.mu.Unlock()
+		if !warned {
+			r.Logger().WarnContext(r.ctx, "write deadline cannot be extended, the stream will be cut off at the write timeout",
+				"timeout", r.options.requestTimeout, "error", err)
+		}
+		return
+	}
+	d.extend(deadline)
+}
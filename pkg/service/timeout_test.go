@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutPrecedence(t *testing.T) {
+	s := newTestService(WithHandlerTimeout(50 * time.Millisecond))
+	deadline := func(w http.ResponseWriter, r *http.Request) {
+		if d, ok := r.Context().Deadline(); ok {
+			w.Header().Set("X-Remaining", time.Until(d).String())
+		}
+		_, flushable := w.(http.Flusher)
+		if flushable {
+			w.Header().Set("X-Flushable", "true")
+		}
+	}
+	s.GET("/default", deadline)
+	s.GET("/route", deadline, WithRouteTimeout(time.Minute))
+	s.GET("/stream", deadline, WithRouteTimeout(0))
+	s.GET("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	tests := []struct {
+		path      string
+		min, max  time.Duration
+		flushable bool
+	}{
+		{"/default", 1, 50 * time.Millisecond, false},
+		{"/route", 50 * time.Millisecond, time.Minute, false},
+		{"/stream", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := serve(s.mux, http.MethodGet, tt.path, nil)
+			var remaining time.Duration
+			if v := rec.Header().Get("X-Remaining"); v != "" {
+				remaining, _ = time.ParseDuration(v)
+			}
+			if remaining < tt.min || remaining > tt.max {
+				t.Errorf("got remaining %v, want between %v and %v", remaining, tt.min, tt.max)
+			}
+			if flushable := rec.Header().Get("X-Flushable") == "true"; flushable != tt.flushable {
+				t.Errorf("got flushable %v, want %v", flushable, tt.flushable)
+			}
+		})
+	}
+	if code := serve(s.mux, http.MethodGet, "/slow", nil).Code; code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestWriteDeadlineCancelsRequestContext(t *testing.T) {
+	s := newTestService(WithRequestTimeout(30 * time.Millisecond))
+	errc := make(chan error, 1)
+	s.GET("/wait", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			errc <- r.Context().Err()
+		case <-time.After(time.Second):
+			errc <- nil
+		}
+	})
+	serve(s.mux, http.MethodGet, "/wait", nil)
+	if err := <-errc; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRequestContextTimeout(t *testing.T) {
+	s := newTestService(WithRequestContextTimeout(20 * time.Millisecond))
+	errc := make(chan error, 1)
+	s.GET("/wait", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			request, _ := RequestFromContext(r.Context())
+			if request.Context().Err() == nil {
+				t.Error("request context not canceled with the handler context")
+			}
+			errc <- r.Context().Err()
+			w.WriteHeader(http.StatusAccepted)
+		case <-time.After(5 * time.Second):
+			errc <- nil
+		}
+	})
+	w := serve(s.mux, http.MethodGet, "/wait", nil)
+	if err := <-errc; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	// The handler still writes its own response
+	if w.Code != http.StatusAccepted {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusAccepted)
+	}
+}
+
+func TestStreamExtendsWriteDeadline(t *testing.T) {
+	s, _ := startTestService(t, WithRequestTimeout(200*time.Millisecond))
+	s.GET("/stream", func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		ch := make(chan []byte)
+		go func() {
+			defer close(ch)
+			for i := 0; i < 6; i++ {
+				time.Sleep(100 * time.Millisecond)
+				select {
+				case ch <- []byte("chunk\n"):
+				case <-request.Context().Done():
+					return
+				}
+			}
+		}()
+		_, _ = BinaryStreamData(request.Context(), *request, ch)()
+	})
+	resp, err := http.Get(s.testURL("/stream"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("got %v after %q, want the stream to outlast the write timeout", err, body)
+	}
+	if want := strings.Repeat("chunk\n", 6); string(body) != want {
+		t.Errorf("got %q, want %q", body, want)
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import "net/http"
+
+// BodyTransform rewrites a request or response body, for example to decrypt requests or wrap responses in an
+// envelope. The response status is mapped from a returned error with StatusFromError.
+type BodyTransform func(r *Request, body []byte) ([]byte, error)
+
+// WithRequestTransform appends transforms run in order on the request body before the middleware added with
+// WithMiddleware and the handler, which read the transformed body. Requests without a body are not transformed.
+func WithRequestTransform(transforms ...BodyTransform) Option {
+	return func(o *Options) {
+		o.requestTransforms = append(o.requestTransforms, transforms...)
+	}
+}
+
+// WithResponseTransform appends transforms run in order on the response body once the handler has returned. The
+// response is buffered up to the response buffer limit to be transformed; responses outgrowing it, flushed by the
+// handler or without a body are sent untransformed. Transforms read the response headers, such as its Content-Type,
+// through the Writer of the request.
+func WithResponseTransform(transforms ...BodyTransform) Option {
+	return func(o *Options) {
+		o.responseTransforms = append(o.responseTransforms, transforms...)
+	}
+}
+
+// transformMiddleware runs the request transforms on the way in and the response transforms on the way out
+func (s *service) transformMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, ok := RequestFromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(s.requestTransforms) > 0 && !s.transformRequest(w, request, r) {
+			return
+		}
+		if len(s.responseTransforms) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		buffered := newBufferedWriter(w, s.responseBufferLimit)
+		next.ServeHTTP(buffered, r)
+		if !buffered.streaming && buffered.body.Len() > 0 {
+			s.transformResponse(buffered, request, r)
+		}
+		buffered.finish()
+	})
+}
+
+// transformRequest replaces the request body with its transformation, responding to the request and reporting false
+// when a transform fails
+func (s *service) transformRequest(w http.ResponseWriter, request *Request, r *http.Request) bool {
+	body, err := request.Body()
+	if err != nil || len(body) == 0 {
+		return s.transformFailed(w, request, r, err)
+	}
+	for _, transform := range s.requestTransforms {
+		if body, err = transform(request, body); err != nil {
+			return s.transformFailed(w, request, r, err)
+		}
+	}
+	request.setBody(body)
+	r.ContentLength = int64(len(body))
+	r.Header.Del("Content-Length")
+	return true
+}
+
+// transformResponse replaces the buffered response body with its transformation, or with an error response when a
+// transform fails
+func (s *service) transformResponse(w *bufferedWriter, request *Request, r *http.Request) {
+	body := w.body.Bytes()
+	var err error
+	for _, transform := range s.responseTransforms {
+		if body, err = transform(request, body); err != nil {
+			break
+		}
+	}
+	w.body.Reset()
+	w.Header().Del("Content-Length")
+	if err != nil {
+		s.transformFailed(w, request, r, err)
+		return
+	}
+	w.body.Write(body)
+}
+
+// transformFailed responds with the status mapped from a transform error, reporting whether there was none
+func (s *service) transformFailed(w http.ResponseWriter, request *Request, r *http.Request, err error) bool {
+	if err == nil {
+		return true
+	}
+	status := StatusFromError(err)
+	request.Logger().ErrorContext(request.Context(), "body transform failed", "error", err, "status", status)
+	s.ErrorResponse(w, r, status, http.StatusText(status))
+	return false
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// envelope wraps JSON responses in a data field
+func envelope(r *Request, body []byte) ([]byte, error) {
+	if !strings.HasPrefix(r.Writer().Header().Get("Content-Type"), "application/json") {
+		return body, nil
+	}
+	return append(append([]byte(`{"data":`), body...), '}'), nil
+}
+
+func TestBodyTransforms(t *testing.T) {
+	upper := func(r *Request, body []byte) ([]byte, error) {
+		return bytes.ToUpper(body), nil
+	}
+	rejectBad := func(r *Request, body []byte) ([]byte, error) {
+		if string(body) == "BAD" {
+			return nil, fmt.Errorf("%w: bad body", ErrBind)
+		}
+		return body, nil
+	}
+	s := newTestService(WithRequestTransform(upper, rejectBad), WithResponseTransform(envelope))
+	s.POST("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, "%q", body)
+	})
+	s.GET("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("plain"))
+	})
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   int
+		want   string
+	}{
+		{"request and response", http.MethodPost, "/echo", "hello", http.StatusOK, `{"data":"HELLO"}`},
+		{"request transform fails", http.MethodPost, "/echo", "bad", http.StatusBadRequest, ""},
+		{"response left as is", http.MethodGet, "/text", "", http.StatusOK, "plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w *httptest.ResponseRecorder
+			if tt.method == http.MethodPost {
+				w = post(s.mux, tt.path, tt.body, nil)
+			} else {
+				w = serve(s.mux, tt.method, tt.path, nil)
+			}
+			if w.Code != tt.code {
+				t.Fatalf("got status %d, want %d", w.Code, tt.code)
+			}
+			if tt.want != "" && w.Body.String() != tt.want {
+				t.Errorf("got body %q, want %q", w.Body.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WithExternalBaseURL sets the scheme, host and optional path prefix under which clients reach the service, such as
+// https://api.example.com/v1, for building absolute URLs with AbsoluteURL
+func WithExternalBaseURL(externalBaseURL string) Option {
+	return func(o *Options) {
+		o.externalBaseURL = externalBaseURL
+	}
+}
+
+// WithMaxURLLength rejects requests whose request target, the path and query as sent by the client, is longer than
+// the limit in bytes with 414 URI Too Long, before filters, routing or middleware run
+func WithMaxURLLength(maxURLLength int) Option {
+	return func(o *Options) {
+		o.maxURLLength = maxURLLength
+	}
+}
+
+// checkURLLength responds with 414 and reports false when the request target exceeds the maximum URL length
+func (s *service) checkURLLength(w http.ResponseWriter, request *Request) bool {
+	if s.maxURLLength <= 0 {
+		return true
+	}
+	r := request.HTTPRequest()
+	target := r.RequestURI
+	if target == "" {
+		target = r.URL.RequestURI()
+	}
+	if len(target) <= s.maxURLLength {
+		return true
+	}
+	s.RequestURITooLong(w, r)
+	return false
+}
+
+func parseBaseURL(baseURL string) (*url.URL, error) {
+	if baseURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid external base URL %q: %w", baseURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid external base URL %q: scheme and host are required", baseURL)
+	}
+	return u, nil
+}
+
+// AbsoluteURL returns the absolute URL of the path, which may include a query, as seen by the client. The scheme and
+// host come from the external base URL when one is set, and otherwise from the request, honouring the forwarded
+// headers normalized by WithProxyHeaders.
+func (r *Request) AbsoluteURL(path string) string {
+	var base url.URL
+	if r.options != nil && r.options.baseURL != nil {
+		base = *r.options.baseURL
+	} else {
+		base.Scheme = "http"
+		if r.options.isSecure(r.httpRequest) {
+			base.Scheme = "https"
+		}
+		base.Host = r.httpRequest.Host
+	}
+	ref, err := url.Parse(path)
+	if err != nil {
+		return base.String() + path
+	}
+	base.Path = strings.TrimSuffix(base.Path, "/") + "/" + strings.TrimPrefix(ref.Path, "/")
+	base.RawPath = ""
+	base.RawQuery = ref.RawQuery
+	base.Fragment = ref.Fragment
+	return base.String()
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAbsoluteURL(t *testing.T) {
+	link := func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		_, _ = w.Write([]byte(request.AbsoluteURL("/users?page=2")))
+	}
+	forwarded := map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "ext.example"}
+	tests := []struct {
+		name   string
+		opts   []Option
+		header map[string]string
+		want   string
+	}{
+		{"external base URL", []Option{WithExternalBaseURL("https://api.example.com/v1/")}, forwarded,
+			"https://api.example.com/v1/users?page=2"},
+		{"request", nil, nil, "http://example.com/users?page=2"},
+		{"trusted proxy", []Option{WithProxyHeaders(true), WithTrustedProxies([]string{"192.0.2.1"})}, forwarded,
+			"https://ext.example/users?page=2"},
+		{"untrusted proxy", []Option{WithProxyHeaders(true)}, forwarded, "http://example.com/users?page=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(tt.opts...)
+			s.GET("/link", link)
+			if got := serve(s.mux, http.MethodGet, "/link", tt.header).Body.String(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+	if _, err := parseBaseURL("/v1"); err == nil {
+		t.Error("got no error for a base URL without a scheme and host")
+	}
+}
+
+func TestMaxURLLength(t *testing.T) {
+	s := newTestService(WithMaxURLLength(16))
+	s.GET("/items", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	tests := []struct {
+		target string
+		code   int
+	}{
+		{"/items?q=abcdef", http.StatusOK},
+		{"/items?q=abcdefg", http.StatusOK},
+		{"/items?q=abcdefgh", http.StatusRequestURITooLong},
+	}
+	for _, tt := range tests {
+		if w := serve(s.mux, http.MethodGet, tt.target, nil); w.Code != tt.code {
+			t.Errorf("%s: got status %d, want %d", tt.target, w.Code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+)
+
+// Validator validates a decoded request body. Errors returned as validator.ValidationErrors, such as those of
+// (*validator.Validate).Struct, are reported as a *ValidationError listing the failed fields.
+type Validator func(v interface{}) error
+
+// WithValidator replaces the validator run by DecodeAndValidate, which defaults to go-playground/validator struct
+// tag validation naming fields by their JSON names
+func WithValidator(validator Validator) Option {
+	return func(o *Options) {
+		o.validator = validator
+	}
+}
+
+// FieldViolation describes a field that failed validation
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ValidationError lists the fields of a request body that failed validation. StatusFromError maps it to 422.
+type ValidationError struct {
+	Violations []FieldViolation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		fields = append(fields, fmt.Sprintf("%s (%s)", violation.Field, violation.Rule))
+	}
+	return "validation failed: " + strings.Join(fields, ", ")
+}
+
+var defaultValidator = newDefaultValidator()
+
+func newDefaultValidator() Validator {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return field.Name
+		}
+		return name
+	})
+	return func(v interface{}) error {
+		// Struct tag validation only applies to structs
+		target := reflect.Indirect(reflect.ValueOf(v))
+		if target.Kind() != reflect.Struct {
+			return nil
+		}
+		return validate.Struct(v)
+	}
+}
+
+// DecodeAndValidate decodes the JSON request body into v and validates it. Malformed bodies are reported as ErrBind
+// and failed validation as a *ValidationError.
+func (r *Request) DecodeAndValidate(v interface{}) error {
+	body, err := r.Body()
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrBind, err)
+	}
+	validate := defaultValidator
+	if r.options != nil && r.options.validator != nil {
+		validate = r.options.validator
+	}
+	return validationError(validate(v))
+}
+
+// validationError converts the field errors reported by go-playground/validator into a *ValidationError
+func validationError(err error) error {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+	violations := make([]FieldViolation, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		// The namespace is prefixed with the name of the top level struct
+		field := fieldError.Namespace()
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+		violations = append(violations, FieldViolation{Field: field, Rule: fieldError.Tag(), Param: fieldError.Param()})
+	}
+	return &ValidationError{Violations: violations}
+}
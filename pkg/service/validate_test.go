@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeAndValidate(t *testing.T) {
+	type user struct {
+		Name  string `json:"name" validate:"required"`
+		Email string `json:"email" validate:"required,email"`
+		Age   int    `json:"age" validate:"gte=0,lte=130"`
+	}
+	tests := []struct {
+		name       string
+		body       string
+		code       int
+		violations []FieldViolation
+	}{
+		{"valid", `{"name":"a","email":"a@example.com"}`, http.StatusOK, nil},
+		{"invalid", `{"email":"a@example.com","age":200}`, http.StatusUnprocessableEntity, []FieldViolation{
+			{Field: "name", Rule: "required"},
+			{Field: "age", Rule: "lte", Param: "130"},
+		}},
+		{"malformed", `{`, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			var u user
+			err := NewRequest(context.Background(), r, httptest.NewRecorder()).DecodeAndValidate(&u)
+			if code := StatusFromError(err); code != tt.code {
+				t.Fatalf("got %v with status %d, want %d", err, code, tt.code)
+			}
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) && !reflect.DeepEqual(validationErr.Violations, tt.violations) {
+				t.Errorf("got violations %+v, want %+v", validationErr.Violations, tt.violations)
+			}
+		})
+	}
+}
+
+func TestWithValidator(t *testing.T) {
+	errCustom := errors.New("custom validation failed")
+	s := newTestService(WithValidator(func(v interface{}) error {
+		return errCustom
+	}))
+	s.POST("/", func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		var v map[string]string
+		if err := request.DecodeAndValidate(&v); !errors.Is(err, errCustom) {
+			t.Errorf("got %v, want %v", err, errCustom)
+		}
+	})
+	s.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`)))
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// ContextKey names a value carried by a request from middleware to handlers
+type ContextKey string
+
+// contextValueKey keeps request values apart from context values stored under other key types
+type contextValueKey struct {
+	key ContextKey
+}
+
+// WithValue stores the value under the key in the request context and returns the request. Middleware passing
+// request.HTTPRequest() on to the next handler hands the value to plain http.Handlers as well. The value is also kept
+// with the request, so that Value finds it even when the context is later rebuilt from an http.Request without it.
+func WithValue(r *Request, key ContextKey, v interface{}) *Request {
+	r.values.set(contextValueKey{key: key}, v)
+	r.setContext(context.WithValue(r.ctx, contextValueKey{key: key}, v))
+	return r
+}
+
+// Value returns the value stored under the key with WithValue, or nil if there is none
+func Value(r *Request, key ContextKey) interface{} {
+	if v, ok := r.values.get(contextValueKey{key: key}); ok {
+		return v
+	}
+	return r.ctx.Value(contextValueKey{key: key})
+}
+
+// requestValues is the key/value store of a request, shared with copies of the request. It holds the values stored
+// with Set under string keys and those stored with WithValue under their contextValueKey.
+type requestValues struct {
+	mu sync.RWMutex
+	m  map[interface{}]interface{}
+}
+
+func (v *requestValues) set(key, value interface{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.m == nil {
+		v.m = make(map[interface{}]interface{})
+	}
+	v.m[key] = value
+}
+
+func (v *requestValues) get(key interface{}) (interface{}, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	value, ok := v.m[key]
+	return value, ok
+}
+
+// Set stores the value under the key for the rest of the request, as a simpler alternative to WithValue for state
+// passed between middleware and handlers. Unlike WithValue, the value is not visible through the request context.
+// Set and Get are safe for concurrent use.
+func (r *Request) Set(key string, v interface{}) {
+	r.values.set(key, v)
+}
+
+// Get returns the value stored under the key with Set, reporting false when there is none
+func (r *Request) Get(key string) (interface{}, bool) {
+	return r.values.get(key)
+}
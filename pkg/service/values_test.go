@@ -0,0 +1,68 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestContextValues(t *testing.T) {
+	const userKey ContextKey = "user"
+	tests := []struct {
+		name string
+		opts []Option
+		// forward passes the http.Request of the Request on rather than the one the middleware received
+		forward bool
+		want    string
+	}{
+		{"forwarded", nil, true, "alice alice <nil>"},
+		{"original request passed on", nil, false, "alice <nil> <nil>"},
+		{"handler timeout", []Option{WithHandlerTimeout(time.Second)}, false, "alice <nil> <nil>"},
+		{"request context timeout", []Option{WithRequestContextTimeout(time.Second)}, false, "alice <nil> <nil>"},
+		{"write timeout", []Option{WithRequestTimeout(time.Second)}, true, "alice alice <nil>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUser := func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					request, _ := RequestFromContext(r.Context())
+					WithValue(request, userKey, "alice")
+					if tt.forward {
+						r = request.HTTPRequest()
+					}
+					next.ServeHTTP(w, r)
+				})
+			}
+			s := newTestService(append(tt.opts, WithMiddleware(setUser))...)
+			s.GET("/", func(w http.ResponseWriter, r *http.Request) {
+				request, _ := RequestFromContext(r.Context())
+				_, _ = fmt.Fprint(w, Value(request, userKey), " ", r.Context().Value(contextValueKey{userKey}), " ",
+					Value(request, "other"))
+			})
+			if got := serve(s.mux, http.MethodGet, "/", nil).Body.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestSetGet(t *testing.T) {
+	setTenant := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request, _ := RequestFromContext(r.Context())
+			request.Set("tenant", r.Header.Get("X-Tenant"))
+			next.ServeHTTP(w, r)
+		})
+	}
+	s := newTestService(WithMiddleware(setTenant), WithHandlerTimeout(time.Second))
+	s.GET("/", func(w http.ResponseWriter, r *http.Request) {
+		request, _ := RequestFromContext(r.Context())
+		tenant, ok := request.Get("tenant")
+		_, missing := request.Get("other")
+		_, _ = fmt.Fprint(w, tenant, " ", ok, " ", missing)
+	})
+	if got := serve(s.mux, http.MethodGet, "/", map[string]string{"X-Tenant": "acme"}).Body.String(); got != "acme true false" {
+		t.Errorf("got %q, want %q", got, "acme true false")
+	}
+}
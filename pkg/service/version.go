@@ -0,0 +1,60 @@
+package service
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// vendorVersion matches the version in vendor media types such as application/vnd.myapp.v2+json
+var vendorVersion = regexp.MustCompile(`vnd\.[^;,]+\.(v[0-9]+)(?:\+|;|,|$)`)
+
+func (s *service) VersionedRoute(method, pattern string, versions map[string]http.HandlerFunc, opts ...RouteOption) {
+	registerVersioned(s, s.NotAcceptable, method, pattern, versions, opts)
+}
+
+func (g *group) VersionedRoute(method, pattern string, versions map[string]http.HandlerFunc, opts ...RouteOption) {
+	registerVersioned(g, g.service.NotAcceptable, method, pattern, versions, opts)
+}
+
+// registerVersioned registers each version under its /{version} prefix, and the unprefixed pattern to select the
+// version requested in the Accept header, defaulting to the latest
+func registerVersioned(router Router, notAcceptable http.HandlerFunc, method, pattern string, versions map[string]http.HandlerFunc, opts []RouteOption) {
+	names := make([]string, 0, len(versions))
+	for version, handler := range versions {
+		names = append(names, version)
+		router.Route(method, joinPath("/"+version, pattern), handler, opts...)
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return versionLess(names[i], names[j])
+	})
+	latest := names[len(names)-1]
+	router.Route(method, pattern, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		version := latest
+		if m := vendorVersion.FindStringSubmatch(r.Header.Get("Accept")); m != nil {
+			version = m[1]
+		}
+		handler, ok := versions[version]
+		if !ok {
+			notAcceptable(w, r)
+			return
+		}
+		handler(w, r)
+	}, opts...)
+}
+
+// versionLess orders versions such as v2 and v10 numerically, falling back to string order
+func versionLess(a, b string) bool {
+	na, errA := strconv.Atoi(strings.TrimPrefix(a, "v"))
+	nb, errB := strconv.Atoi(strings.TrimPrefix(b, "v"))
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return na < nb
+}
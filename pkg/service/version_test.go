@@ -0,0 +1,44 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestVersionedRoute(t *testing.T) {
+	s := newTestService()
+	s.VersionedRoute(http.MethodGet, "/resource", map[string]http.HandlerFunc{
+		"v1":  writeBody("v1"),
+		"v2":  writeBody("v2"),
+		"v10": writeBody("v10"),
+	})
+	s.Group("/api").VersionedRoute(http.MethodGet, "/grouped", map[string]http.HandlerFunc{"v1": writeBody("grouped")})
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		code   int
+		body   string
+	}{
+		{"prefix", "/v1/resource", "", http.StatusOK, "v1"},
+		{"accept header", "/resource", "application/vnd.myapp.v2+json", http.StatusOK, "v2"},
+		{"latest by default", "/resource", "", http.StatusOK, "v10"},
+		{"unknown version", "/resource", "application/vnd.myapp.v3+json", http.StatusNotAcceptable, ""},
+		{"group prefix", "/api/v1/grouped", "", http.StatusOK, "grouped"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header map[string]string
+			if tt.accept != "" {
+				header = map[string]string{"Accept": tt.accept}
+			}
+			w := serve(s.mux, http.MethodGet, tt.path, header)
+			if w.Code != tt.code {
+				t.Errorf("got status %d, want %d", w.Code, tt.code)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("got %q, want %q", w.Body.String(), tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"github.com/gorilla/websocket"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WebSocketHandler serves a WebSocket connection. The connection is closed once the handler returns, or when the
+// request context is canceled, as it is when the service stops.
+type WebSocketHandler func(r *Request, conn *websocket.Conn)
+
+// webSocketRoute is a WebSocket endpoint registered with WithWebSocket
+type webSocketRoute struct {
+	pattern string
+	handler WebSocketHandler
+}
+
+// WithWebSocket serves WebSocket connections upgraded from GET requests to the pattern. Upgrades from another origin
+// than the host of the request are rejected with 403 unless allowed with WithWebSocketOrigins. The route passes
+// through the middleware chain like any other and is registered as a streaming route, free of handler timeouts and
+// the connection write deadline.
+func WithWebSocket(pattern string, handler WebSocketHandler) Option {
+	return func(o *Options) {
+		o.webSockets = append(o.webSockets, webSocketRoute{pattern: pattern, handler: handler})
+	}
+}
+
+// WithWebSocketOrigins allows WebSocket upgrades from the origins, such as https://app.example.com, in addition to
+// the host of the request. The origin * allows any origin, which exposes the endpoints to cross-site WebSocket
+// hijacking when they rely on cookies.
+func WithWebSocketOrigins(origins []string) Option {
+	return func(o *Options) {
+		o.webSocketOrigins = origins
+	}
+}
+
+// checkWebSocketOrigin reports whether an upgrade request comes from the host itself or from an allowed origin.
+// Requests without an Origin header do not come from a browser and are allowed.
+func (o *Options) checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range o.webSocketOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// webSocketHandler upgrades the request and serves the connection with the handler
+func (s *service) webSocketHandler(handler WebSocketHandler) http.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		CheckOrigin: s.checkWebSocketOrigin,
+		Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+			if status == http.StatusForbidden {
+				s.Forbidden(w, r)
+				return
+			}
+			s.ErrorResponse(w, r, status, http.StatusText(status))
+		},
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, ok := RequestFromContext(r.Context())
+		if !ok {
+			s.InternalServerError(w, r)
+			return
+		}
+		conn, err := upgrader.Upgrade(hijackWriter{w}, r, nil)
+		if err != nil {
+			request.Logger().DebugContext(request.Context(), "websocket upgrade failed", "error", err)
+			return
+		}
+		stop := context.AfterFunc(request.Context(), func() {
+			_ = conn.Close()
+		})
+		defer func() {
+			stop()
+			_ = conn.Close()
+		}()
+		handler(request, conn)
+	}
+}
+
+// hijackWriter exposes the connection of a wrapped writer through http.Hijacker, which the upgrader requires
+type hijackWriter struct {
+	http.ResponseWriter
+}
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"github.com/gorilla/websocket"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoWebSocket echoes messages until the connection is closed
+func echoWebSocket(r *Request, conn *websocket.Conn) {
+	for {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err := conn.WriteMessage(messageType, message); err != nil {
+			return
+		}
+	}
+}
+
+// webSocketURL returns the WebSocket URL of the path on a service started with startTestService
+func (s *service) webSocketURL(path string) string {
+	return "ws" + strings.TrimPrefix(s.testURL(path), "http")
+}
+
+func TestWebSocket(t *testing.T) {
+	s, _ := startTestService(t, WithWebSocket("/ws", echoWebSocket))
+	conn, _, err := websocket.DefaultDialer.Dial(s.webSocketURL("/ws"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(message) != "hello" {
+		t.Errorf("got %q, want hello", message)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if netErr, ok := err.(net.Error); err == nil || ok && netErr.Timeout() {
+		t.Errorf("got %v after stopping, want the connection closed", err)
+	}
+}
+
+func TestWebSocketOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		origin  string
+		allowed bool
+	}{
+		{"no origin", nil, "", true},
+		{"same origin", nil, "http://HOST", true},
+		{"cross origin denied by default", nil, "https://evil.example", false},
+		{"allowed origin", []string{"https://app.example/"}, "https://app.example", true},
+		{"other origin", []string{"https://app.example"}, "https://evil.example", false},
+		{"any origin", []string{"*"}, "https://evil.example", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := startTestService(t, WithWebSocket("/ws", echoWebSocket), WithWebSocketOrigins(tt.origins))
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", strings.Replace(tt.origin, "HOST", s.listener.Addr().String(), 1))
+			}
+			conn, resp, err := websocket.DefaultDialer.Dial(s.webSocketURL("/ws"), header)
+			if tt.allowed {
+				if err != nil {
+					t.Fatalf("got %v, want the upgrade allowed", err)
+				}
+				conn.Close()
+				return
+			}
+			if err == nil {
+				conn.Close()
+				t.Fatal("got the upgrade allowed, want it rejected")
+			}
+			if resp == nil || resp.StatusCode != http.StatusForbidden {
+				t.Errorf("got response %v, want 403", resp)
+			}
+		})
+	}
+}
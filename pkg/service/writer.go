@@ -0,0 +1,64 @@
+package service
+
+import "net/http"
+
+// TrackingWriter wraps an http.ResponseWriter and records whether the handler responded, the status and the number of
+// bytes written. Middleware can use it to provide a default response when a handler returns without writing one.
+type TrackingWriter struct {
+	http.ResponseWriter
+	status      int
+	written     int64
+	wroteHeader bool
+}
+
+// NewTrackingWriter returns a TrackingWriter writing to w
+func NewTrackingWriter(w http.ResponseWriter) *TrackingWriter {
+	return &TrackingWriter{ResponseWriter: w}
+}
+
+func (w *TrackingWriter) WriteHeader(status int) {
+	// Informational responses do not commit the final status
+	if !w.wroteHeader && (status >= 200 || status == http.StatusSwitchingProtocols) {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *TrackingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.status = http.StatusOK
+		w.wroteHeader = true
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *TrackingWriter) Flush() {
+	if !w.wroteHeader {
+		w.status = http.StatusOK
+		w.wroteHeader = true
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer
+func (w *TrackingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status returns the status written to the client, or http.StatusOK if nothing has been written yet
+func (w *TrackingWriter) Status() int {
+	if !w.wroteHeader {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Written reports whether a status or body has been written
+func (w *TrackingWriter) Written() bool {
+	return w.wroteHeader
+}
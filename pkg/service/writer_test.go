@@ -0,0 +1,47 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrackingWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		written bool
+		status  int
+	}{
+		{"nothing", func(w http.ResponseWriter, r *http.Request) {}, false, http.StatusOK},
+		{"status", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }, true,
+			http.StatusNoContent},
+		{"body", writeBody("ok"), true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewTrackingWriter(httptest.NewRecorder())
+			tt.handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Written() != tt.written || w.Status() != tt.status {
+				t.Errorf("got written %v with status %d, want %v with %d", w.Written(), w.Status(), tt.written, tt.status)
+			}
+		})
+	}
+}
+
+func TestTrackingWriterDefaultResponse(t *testing.T) {
+	fallback := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tw := NewTrackingWriter(w)
+			next.ServeHTTP(tw, r)
+			if !tw.Written() {
+				http.Error(w, "nothing here", http.StatusNotFound)
+			}
+		})
+	}
+	s := newTestService(WithMiddleware(fallback))
+	s.GET("/noop", func(w http.ResponseWriter, r *http.Request) {})
+	if w := serve(s.mux, http.MethodGet, "/noop", nil); w.Code != http.StatusNotFound || w.Body.String() != "nothing here\n" {
+		t.Errorf("got status %d with body %q, want the default response", w.Code, w.Body.String())
+	}
+}